@@ -4,6 +4,8 @@ package batcher
 import (
 	"sync"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 type work struct {
@@ -15,12 +17,13 @@ type work struct {
 type Batcher struct {
 	timeout   time.Duration
 	prefilter func(interface{}) error
+	clock     clock.Clock
 
 	lock         sync.Mutex
 	submit       chan *work
 	doWork       func([]interface{}) error
 	batchCounter sync.WaitGroup
-	flushTimer   *time.Timer
+	flushTimer   clock.Timer
 }
 
 // New constructs a new batcher that will batch all calls to Run that occur within
@@ -31,9 +34,18 @@ func New(timeout time.Duration, doWork func([]interface{}) error) *Batcher {
 	return &Batcher{
 		timeout: timeout,
 		doWork:  doWork,
+		clock:   clock.New(),
 	}
 }
 
+// WithClock configures the batcher to use the given clock instead of the real one when waiting
+// for the batch timeout, for example a clock.Fake in tests. It cannot safely be called if Run has
+// already been invoked.
+func (b *Batcher) WithClock(c clock.Clock) *Batcher {
+	b.clock = c
+	return b
+}
+
 // Run runs the work function with the given parameter, possibly
 // including it in a batch with other calls to Run that occur within the
 // specified timeout. It is safe to call Run concurrently on the same batcher.
@@ -76,7 +88,7 @@ func (b *Batcher) submitWork(w *work) {
 		b.batchCounter.Add(1)
 		b.submit = make(chan *work, 4)
 		go b.batch(b.submit)
-		b.flushTimer = time.AfterFunc(b.timeout, b.flushCurrentBatch)
+		b.flushTimer = b.clock.AfterFunc(b.timeout, b.flushCurrentBatch)
 	}
 
 	// then add this work to the current batch
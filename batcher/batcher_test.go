@@ -6,6 +6,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 var errSomeError = errors.New("errSomeError")
@@ -158,6 +160,36 @@ func TestBatcherMultipleBatches(t *testing.T) {
 	}
 }
 
+func TestBatcherClock(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	var ran int32
+	b := New(10*time.Millisecond, func(params []interface{}) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	}).WithClock(c)
+
+	result := make(chan error)
+	go func() {
+		result <- b.Run(nil)
+	}()
+
+	// nothing runs until the timeout passes
+	c.BlockUntil(1)
+	c.Advance(9 * time.Millisecond)
+	time.Sleep(1 * time.Millisecond)
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Error("batch ran early")
+	}
+
+	c.Advance(1 * time.Millisecond)
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&ran) != 1 {
+		t.Error("batch did not run")
+	}
+}
+
 func ExampleBatcher() {
 	b := New(10*time.Millisecond, func(params []interface{}) error {
 		// do something with the batch of parameters
@@ -0,0 +1,19 @@
+package batcher
+
+import (
+	"time"
+
+	"github.com/eapache/go-resiliency/config"
+)
+
+// Config describes a Batcher in a form which can be loaded from a configuration file, for use with
+// FromConfig.
+type Config struct {
+	Timeout config.Duration `json:"timeout"`
+}
+
+// FromConfig constructs a Batcher as described by the given configuration, which calls doWork as
+// in New.
+func FromConfig(c Config, doWork func([]interface{}) error) *Batcher {
+	return New(time.Duration(c.Timeout), doWork)
+}
@@ -0,0 +1,22 @@
+package batcher
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFromConfig(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"timeout": "10ms"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	b := FromConfig(c, returnsSuccess)
+	if b.timeout != 10*time.Millisecond {
+		t.Error("incorrect timeout", b.timeout)
+	}
+	if err := b.Run(nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,135 @@
+package breaker
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+// Adaptive implements client-side adaptive throttling, as described in the "Handling Overload"
+// chapter of Google's Site Reliability Engineering book. Rather than switching between open and
+// closed, it tracks how many requests were attempted and how many were accepted by the dependency
+// over a rolling time window, and rejects each new request locally with probability
+//
+//	max(0, (requests - k*accepts) / (requests + 1))
+//
+// so that the load sent to an unhealthy dependency falls off smoothly as its acceptance rate drops,
+// and recovers smoothly as it improves. Requests rejected locally still count as requests, which
+// is what drives the rejection probability up.
+type Adaptive struct {
+	k     float64
+	clock clock.Clock
+	class retrier.Classifier
+
+	lock   sync.Mutex
+	window *timeWindow
+	rand   *rand.Rand
+}
+
+// NewAdaptive constructs a new adaptive throttler. The multiplier "k" controls how aggressively it
+// rejects requests: with k=2 it allows roughly twice as many requests as are accepted before it
+// starts rejecting any; lower values reject sooner, higher values waste more effort on requests
+// which are likely to fail. The window is divided into "buckets" equal parts, each of which expires
+// as a unit, as with Breaker.WithFailureRate.
+func NewAdaptive(k float64, window time.Duration, buckets int) *Adaptive {
+	return &Adaptive{
+		k:      k,
+		clock:  clock.New(),
+		class:  retrier.DefaultClassifier{},
+		window: newTimeWindow(window, buckets),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// WithClock configures the throttler to use the given clock instead of the real one, for example
+// a clock.Fake in tests. It must be called before the throttler is used.
+func (a *Adaptive) WithClock(c clock.Clock) *Adaptive {
+	a.clock = c
+	return a
+}
+
+// WithClassifier configures the throttler to use the given classifier to decide which errors mean
+// the request was not accepted, with the same meaning as for Breaker.WithClassifier: errors
+// classified as retrier.Retry were not accepted, errors classified as retrier.Succeed were, and
+// errors classified as retrier.Fail are ignored entirely. It must be called before the throttler
+// is used.
+func (a *Adaptive) WithClassifier(class retrier.Classifier) *Adaptive {
+	if class == nil {
+		class = retrier.DefaultClassifier{}
+	}
+	a.class = class
+	return a
+}
+
+// Run will either return ErrBreakerOpen immediately if the request is rejected by the throttler,
+// or it will run the given function and pass along its return value. Panics in the work function
+// count as requests which were not accepted, and are then re-panicked. It is safe to call Run
+// concurrently on the same Adaptive.
+func (a *Adaptive) Run(work func() error) error {
+	if a.reject() {
+		return ErrBreakerOpen
+	}
+
+	panicked := true
+	defer func() {
+		if panicked {
+			a.record(false)
+		}
+	}()
+
+	result := work()
+	panicked = false
+
+	switch a.class.Classify(result) {
+	case retrier.Succeed:
+		a.record(true)
+	case retrier.Retry:
+		a.record(false)
+	}
+
+	return result
+}
+
+// RejectionProbability returns the probability with which a request made right now would be
+// rejected, e.g. for monitoring.
+func (a *Adaptive) RejectionProbability() float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.probability(a.clock.Now())
+}
+
+func (a *Adaptive) reject() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.clock.Now()
+	if a.rand.Float64() < a.probability(now) {
+		// rejected requests still count as requests which were not accepted
+		a.window.record(now, true, false)
+		return true
+	}
+	return false
+}
+
+func (a *Adaptive) record(accepted bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.window.record(a.clock.Now(), !accepted, false)
+}
+
+// probability must be called with the lock held.
+func (a *Adaptive) probability(now time.Time) float64 {
+	requests, notAccepted, _ := a.window.counts(now)
+	accepts := requests - notAccepted
+
+	p := (float64(requests) - a.k*float64(accepts)) / float64(requests+1)
+	if p < 0 {
+		return 0
+	}
+	return p
+}
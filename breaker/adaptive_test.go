@@ -0,0 +1,93 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+func TestAdaptive(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	a := NewAdaptive(2, 10*time.Second, 10).WithClock(c)
+
+	// nothing is rejected while the dependency is healthy
+	for i := 0; i < 100; i++ {
+		if err := a.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if a.RejectionProbability() != 0 {
+		t.Error("incorrect probability", a.RejectionProbability())
+	}
+
+	// failures are passed along, and eventually some calls are rejected locally
+	rejected := 0
+	for i := 0; i < 1000; i++ {
+		switch err := a.Run(returnsError); err {
+		case ErrBreakerOpen:
+			rejected++
+		case errSomeError:
+		default:
+			t.Error(err)
+		}
+	}
+	if rejected == 0 {
+		t.Error("no requests rejected")
+	}
+	// with 1100 requests and 100 accepts, p = (1100 - 200) / 1101
+	if p := a.RejectionProbability(); p < 0.81 || p > 0.82 {
+		t.Error("incorrect probability", p)
+	}
+
+	// the window expires
+	c.Advance(10 * time.Second)
+	if a.RejectionProbability() != 0 {
+		t.Error("incorrect probability", a.RejectionProbability())
+	}
+}
+
+func TestAdaptiveProbability(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	a := NewAdaptive(2, 10*time.Second, 10).WithClock(c)
+
+	// 10 requests, 4 accepted: (10 - 2*4) / 11
+	for i := 0; i < 10; i++ {
+		a.record(i < 4)
+	}
+	if p := a.RejectionProbability(); p != 2.0/11.0 {
+		t.Error("incorrect probability", p)
+	}
+}
+
+func TestAdaptiveClassifier(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	errIgnored := errors.New("errIgnored")
+	a := NewAdaptive(1, 10*time.Second, 10).
+		WithClock(c).
+		WithClassifier(retrier.BlacklistClassifier{errIgnored})
+
+	for i := 0; i < 10; i++ {
+		if err := a.Run(func() error { return errIgnored }); err != errIgnored {
+			t.Error(err)
+		}
+	}
+	if a.RejectionProbability() != 0 {
+		t.Error("incorrect probability", a.RejectionProbability())
+	}
+
+	// panics count as requests which were not accepted
+	func() {
+		defer func() {
+			if val := recover(); val != "foo" {
+				t.Error("incorrect panic", val)
+			}
+		}()
+		a.Run(alwaysPanics)
+	}()
+	if p := a.RejectionProbability(); p != 0.5 {
+		t.Error("incorrect probability", p)
+	}
+}
@@ -2,16 +2,52 @@
 package breaker
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+	"github.com/eapache/go-resiliency/retrier"
 )
 
 // ErrBreakerOpen is the error returned from Run() when the function is not executed
 // because the breaker is currently open.
 var ErrBreakerOpen = errors.New("circuit breaker is open")
 
+// ErrSlowCall is the error reported as the cause of a state change (see OnStateChange) when the
+// breaker was tripped by calls exceeding the slow-call threshold, rather than by an error. It is
+// never returned from Run.
+var ErrSlowCall = errors.New("circuit breaker: call exceeded the slow-call threshold")
+
+// PanicError is the error recorded by the breaker when the work function panics. It is returned
+// from Run in place of re-panicking if the breaker was configured WithPanicRecovery, and is also
+// reported as the cause of any state change the panic triggers (see OnStateChange).
+type PanicError struct {
+	// Value is the value the work function panicked with.
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("circuit breaker: work function panicked: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it was an error, so that errors.Is and errors.As see through it.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
 // State is a type representing the possible states of a circuit breaker.
 type State uint32
 
@@ -19,17 +55,99 @@ const (
 	Closed State = iota
 	Open
 	HalfOpen
+	// ForcedOpen is the state of a breaker which has been held open by ForceOpen.
+	ForcedOpen
+	// ForcedClosed is the state of a breaker which has been held closed by ForceClosed.
+	ForcedClosed
 )
 
+// String returns a human-readable name for the State.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	case ForcedOpen:
+		return "forced-open"
+	case ForcedClosed:
+		return "forced-closed"
+	default:
+		return fmt.Sprintf("State(%d)", uint32(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, using the same names as String.
+func (s State) MarshalText() ([]byte, error) {
+	if s > ForcedClosed {
+		return nil, fmt.Errorf("circuit breaker: invalid state %d", uint32(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names returned by String.
+func (s *State) UnmarshalText(text []byte) error {
+	for candidate := Closed; candidate <= ForcedClosed; candidate++ {
+		if candidate.String() == string(text) {
+			*s = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("circuit breaker: unknown state %q", text)
+}
+
 // Breaker implements the circuit-breaker resiliency pattern
 type Breaker struct {
+	// accessed atomically, so kept first for 64-bit alignment
+	counters   counters
+	stateSince int64
+	openUntil  int64
+
 	errorThreshold, successThreshold int
 	timeout                          time.Duration
+	clock                            clock.Clock
 
 	lock              sync.Mutex
 	state             State
 	errors, successes int
 	lastError         time.Time
+
+	windowLock  sync.Mutex // protects window, so Metrics need not take lock
+	window      window
+	failureRate float64
+	minRequests int
+
+	openBackoff []time.Duration
+	openJitter  float64
+	opens       int
+	rand        *rand.Rand
+
+	healthCheck     func() error
+	healthSuccesses int
+	healthTimer     clock.Timer
+	healthDue       int64 // when the pending check is due, while openUntil is held at MaxInt64
+	closed          bool
+
+	halfOpenLimit int
+	probes        int
+	generation    uint64
+
+	slowThreshold time.Duration
+	slowRate      float64
+
+	class              retrier.Classifier
+	countContextErrors bool
+	recoverPanics      bool
+
+	store        Store
+	onStoreError func(error)
+	storeErr     error
+
+	parent *Breaker
+
+	events notifier
 }
 
 // New constructs a new circuit-breaker that starts closed.
@@ -38,24 +156,198 @@ type Breaker struct {
 // breaker half-closes after "timeout". From half-open, the breaker closes
 // after "successThreshold" consecutive successes, or opens on a single error.
 func New(errorThreshold, successThreshold int, timeout time.Duration) *Breaker {
-	return &Breaker{
+	b := &Breaker{
 		errorThreshold:   errorThreshold,
 		successThreshold: successThreshold,
 		timeout:          timeout,
+		clock:            clock.New(),
+		class:            retrier.DefaultClassifier{},
+	}
+	b.stateSince = b.clock.Now().UnixNano()
+	return b
+}
+
+// WithClock configures the breaker to use the given clock instead of the real one, for example
+// a clock.Fake in tests. It must be called before the breaker is used.
+func (b *Breaker) WithClock(c clock.Clock) *Breaker {
+	b.clock = c
+	b.stateSince = c.Now().UnixNano()
+	return b
+}
+
+// WithFailureRate switches the breaker from counting errors to tracking the rate of failures
+// over a rolling time window. The window is divided into "buckets" equal parts, each of which
+// expires as a unit. While closed, the breaker opens once at least "minimumRequests" calls have
+// been seen within the window and the fraction of them that failed reaches "rate" (a value between
+// 0.0 and 1.0). The error threshold passed to New is ignored in this mode; the timeout and success
+// threshold behave as usual. It must be called before the breaker is used.
+func (b *Breaker) WithFailureRate(rate float64, minimumRequests int, window time.Duration, buckets int) *Breaker {
+	b.window = newTimeWindow(window, buckets)
+	b.failureRate = rate
+	b.minRequests = minimumRequests
+	return b
+}
+
+// WithCountedFailureRate is like WithFailureRate, except that it tracks the outcome of the last
+// "size" calls rather than all calls within a period of time. This gives more predictable behaviour
+// for services with low or bursty traffic, where a time window may contain very few calls. While
+// closed, the breaker opens once at least "minimumRequests" calls have been recorded and the fraction
+// of them that failed reaches "rate". It must be called before the breaker is used.
+func (b *Breaker) WithCountedFailureRate(rate float64, minimumRequests, size int) *Breaker {
+	b.window = newCountWindow(size)
+	b.failureRate = rate
+	b.minRequests = minimumRequests
+	return b
+}
+
+// WithOpenBackoff configures how long the breaker stays open before half-closing, in place of
+// the fixed timeout. The value at each index of the backoff is the time spent open after that many
+// consecutive failed half-open trials, so the first time the breaker opens it waits backoff[0],
+// if the trial then fails it waits backoff[1], and so on; the final value is re-used once the backoff
+// is exhausted, acting as a cap. The count is reset when the breaker closes. Any of the back-off
+// generators in the retrier package can be used, for example:
+//
+//	b := breaker.New(3, 1, 5*time.Second).WithOpenBackoff(retrier.LimitedExponentialBackoff(10, 5*time.Second, 5*time.Minute), 0.1)
+//
+// Each duration is adjusted by a random amount up to "jitter", a factor between 0.0 and 1.0 (values
+// outside this range are treated as 0), as with Retrier.SetJitter. The timeout passed to New is
+// still used to expire errors while closed. It must be called before the breaker is used.
+func (b *Breaker) WithOpenBackoff(backoff []time.Duration, jitter float64) *Breaker {
+	if jitter < 0 || jitter > 1 {
+		jitter = 0
+	}
+	b.openBackoff = backoff
+	b.openJitter = jitter
+	b.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	return b
+}
+
+// WithHalfOpenLimit limits the number of calls the breaker lets through concurrently while it is
+// half-open, to avoid flooding a recovering dependency. Once "calls" trial calls are in progress,
+// further calls are rejected with ErrBreakerOpen until one of them completes. By default there is
+// no limit. It must be called before the breaker is used.
+func (b *Breaker) WithHalfOpenLimit(calls int) *Breaker {
+	b.halfOpenLimit = calls
+	return b
+}
+
+// WithSlowCallThreshold configures the breaker to treat calls which take longer than "duration" as
+// unhealthy, even if they succeed. When the breaker tracks a failure rate (see WithFailureRate and
+// WithCountedFailureRate) it also opens once at least "minimumRequests" calls are in the window and
+// the fraction of them that were slow reaches "rate". In the default error-counting mode there is no
+// window to compute a rate over, so each slow call counts as an error and "rate" is unused. In either
+// mode a slow call while half-open re-opens the breaker. The return value of a slow call is still
+// passed along to the caller unchanged. It must be called before the breaker is used.
+func (b *Breaker) WithSlowCallThreshold(duration time.Duration, rate float64) *Breaker {
+	b.slowThreshold = duration
+	b.slowRate = rate
+	return b
+}
+
+// WithClassifier configures the breaker to use the given classifier (from the retrier package) to
+// decide how each error returned by the work function is accounted for. Errors classified as
+// retrier.Retry indicate a (possibly transient) problem with the dependency, and count as failures.
+// Errors classified as retrier.Succeed count as successes, and errors classified as retrier.Fail
+// indicate a problem with the individual request rather than the dependency (for example a
+// validation error), and are ignored entirely. Regardless of classification, the error is always
+// passed along to the caller, and panics always count as failures. By default every non-nil error
+// is a failure, as with retrier.DefaultClassifier. It must be called before the breaker is used.
+func (b *Breaker) WithClassifier(class retrier.Classifier) *Breaker {
+	if class == nil {
+		class = retrier.DefaultClassifier{}
 	}
+	b.class = class
+	return b
+}
+
+// WithPanicRecovery configures the breaker to recover panics in the work function and return them
+// as a *PanicError, carrying the panic value and the stack trace at the point of the panic, instead
+// of re-panicking. This is strongly recommended when using Go, since a re-panic in the goroutine
+// running the work function would otherwise crash the entire program. Either way, panics count as
+// failures. It must be called before the breaker is used.
+func (b *Breaker) WithPanicRecovery() *Breaker {
+	b.recoverPanics = true
+	return b
+}
+
+// WithCountContextErrors configures the breaker to count errors caused by the caller's own context
+// being cancelled or exceeding its deadline during RunCtx as failures. By default such errors are
+// ignored, since they say nothing about the health of the protected dependency. It must be called
+// before the breaker is used.
+func (b *Breaker) WithCountContextErrors() *Breaker {
+	b.countContextErrors = true
+	return b
+}
+
+// OnStateChange registers a function to be called every time the breaker changes state. Listeners
+// are called in the order they were registered, from a separate goroutine, so they never block
+// calls made through the breaker; events are delivered in the order the transitions occurred.
+// Listeners should not take too long however, as events queue up behind them; if more than 1024
+// events are waiting, the oldest are dropped, so a listener which blocks indefinitely only misses
+// events rather than leaking memory, and always receives the most recent ones. Note that the
+// transition from open to half-open happens lazily, the next time the breaker is used (or its
+// state is checked) after the timeout has passed, and is reported at that time. It is safe to call
+// OnStateChange concurrently with itself and with any other method on the same Breaker.
+func (b *Breaker) OnStateChange(fn func(StateChange)) {
+	b.events.subscribe(fn)
 }
 
 // Run will either return ErrBreakerOpen immediately if the circuit-breaker is
 // already open, or it will run the given function and pass along its return
 // value. It is safe to call Run concurrently on the same Breaker.
 func (b *Breaker) Run(work func() error) error {
-	state := b.GetState()
+	p, err := b.admit()
+	if err != nil {
+		return err
+	}
+
+	return b.doWork(context.Background(), p, work)
+}
 
-	if state == Open {
-		return ErrBreakerOpen
+// RunCtx is like Run, except that it passes the given context to the work function. If the context
+// is already done, RunCtx returns its error immediately without running the function. If the work
+// function returns an error caused by the context being cancelled or exceeding its deadline (as
+// determined by errors.Is against ctx.Err()), that error is not counted against the breaker, unless
+// the breaker was configured WithCountContextErrors. It is safe to call RunCtx concurrently on the
+// same Breaker.
+func (b *Breaker) RunCtx(ctx context.Context, work func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p, err := b.admit()
+	if err != nil {
+		return err
 	}
 
-	return b.doWork(state, work)
+	return b.doWork(ctx, p, func() error {
+		return work(ctx)
+	})
+}
+
+// Execute is like Breaker.Run, except that the work function also returns a value, which is
+// passed along to the caller. If the function is not run because the breaker is open, the zero
+// value of T is returned along with ErrBreakerOpen.
+func Execute[T any](b *Breaker, work func() (T, error)) (T, error) {
+	var ret T
+	err := b.Run(func() error {
+		var err error
+		ret, err = work()
+		return err
+	})
+	return ret, err
+}
+
+// ExecuteCtx is like Breaker.RunCtx, except that the work function also returns a value, which is
+// passed along to the caller. If the function is not run, the zero value of T is returned.
+func ExecuteCtx[T any](ctx context.Context, b *Breaker, work func(ctx context.Context) (T, error)) (T, error) {
+	var ret T
+	err := b.RunCtx(ctx, func(ctx context.Context) error {
+		var err error
+		ret, err = work(ctx)
+		return err
+	})
+	return ret, err
 }
 
 // Go will either return ErrBreakerOpen immediately if the circuit-breaker is
@@ -64,107 +356,476 @@ func (b *Breaker) Run(work func() error) error {
 // the return value of the function. It is safe to call Go concurrently on the
 // same Breaker.
 func (b *Breaker) Go(work func() error) error {
-	state := b.GetState()
-
-	if state == Open {
-		return ErrBreakerOpen
+	p, err := b.admit()
+	if err != nil {
+		return err
 	}
 
 	// errcheck complains about ignoring the error return value, but
 	// that's on purpose; if you want an error from a goroutine you have to
 	// get it over a channel or something
-	go b.doWork(state, work)
+	go b.doWork(context.Background(), p, work)
 
 	return nil
 }
 
+// GoResult is like Go, except that it returns a channel on which the return value of the work
+// function is delivered once it completes. The channel is buffered, so the result is never lost and
+// the goroutine never blocks even if nobody reads it, and it is closed after the result is sent. If
+// the breaker is open, GoResult returns a nil channel and ErrBreakerOpen immediately, without
+// running the function. If the function panics, the breaker's panic policy applies as usual (see
+// WithPanicRecovery); when panics are recovered the *PanicError is delivered on the channel. It is
+// safe to call GoResult concurrently on the same Breaker.
+func (b *Breaker) GoResult(work func() error) (<-chan error, error) {
+	p, err := b.admit()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer close(result)
+		result <- b.doWork(context.Background(), p, work)
+	}()
+
+	return result, nil
+}
+
+// GoCallback is like GoResult, except that instead of returning a channel it calls done with the
+// return value of the work function once it completes, from the goroutine that ran the function.
+// If the breaker is open, done is not called and ErrBreakerOpen is returned immediately.
+func (b *Breaker) GoCallback(work func() error, done func(error)) error {
+	p, err := b.admit()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		done(b.doWork(context.Background(), p, work))
+	}()
+
+	return nil
+}
+
+// ForceOpen holds the breaker open until Reset is called (or it is forced closed), regardless of
+// the outcome of any calls or the timeout. While forced open every call is rejected with
+// ErrBreakerOpen and GetState returns ForcedOpen. This is intended for operators to manually shed
+// load from a dependency, for example during an incident.
+func (b *Breaker) ForceOpen() {
+	b.lock.Lock()
+	defer b.unlock()
+
+	b.update(func() {
+		b.changeState(ForcedOpen, nil)
+	})
+}
+
+// ForceClosed holds the breaker closed until Reset is called (or it is forced open), regardless of
+// the outcome of any calls. While forced closed every call is let through, none of their results
+// are counted, and GetState returns ForcedClosed. This is intended for operators to manually bypass
+// a misbehaving breaker.
+func (b *Breaker) ForceClosed() {
+	b.lock.Lock()
+	defer b.unlock()
+
+	b.update(func() {
+		b.changeState(ForcedClosed, nil)
+	})
+}
+
+// Reset releases any ForceOpen or ForceClosed override and returns the breaker to the closed state
+// with all of its counters cleared, as if it had just been constructed.
+func (b *Breaker) Reset() {
+	b.lock.Lock()
+	defer b.unlock()
+
+	b.update(func() {
+		b.closeBreaker(nil)
+	})
+}
+
 // GetState returns the current State of the circuit-breaker at the moment
 // that it is called.
 func (b *Breaker) GetState() State {
-	return (State)(atomic.LoadUint32((*uint32)(&b.state)))
+	state := (State)(atomic.LoadUint32((*uint32)(&b.state)))
+
+	expired := state == Open && !b.clock.Now().Before(time.Unix(0, atomic.LoadInt64(&b.openUntil)))
+
+	if expired || b.store != nil {
+		// the timeout has passed, so we are really half-open; the transition
+		// happens lazily here rather than on a timer (and with a store, the
+		// state may have been changed by another breaker)
+		b.lock.Lock()
+		defer b.unlock()
+
+		b.refresh()
+		state = b.state
+	}
+
+	return state
 }
 
-func (b *Breaker) doWork(state State, work func() error) error {
-	var panicValue interface{}
+// Close stops all background activity associated with the breaker: any state-change events which
+// have not yet been delivered are discarded, no further events are delivered, and any pending
+// health check (see WithHealthCheck) is cancelled. The breaker otherwise continues to work as normal,
+// except that without health checks it half-opens after the open timeout as if none had been
+// configured. Unless a health check is configured it is not necessary to call Close to avoid leaking
+// goroutines; the breaker only runs goroutines while it has events to deliver, and transitions from
+// open to half-open are computed when the breaker is next used rather than by a timer. Close is safe
+// to call more than once.
+func (b *Breaker) Close() {
+	b.events.close()
 
-	result := func() error {
-		defer func() {
-			panicValue = recover()
-		}()
-		return work()
-	}()
+	b.lock.Lock()
+	defer b.unlock()
 
-	if result == nil && panicValue == nil && state == Closed {
-		// short-circuit the normal, success path without contending
-		// on the lock
-		return nil
+	b.closed = true
+	if b.healthTimer != nil {
+		b.healthTimer.Stop()
+		b.healthTimer = nil
+		// nothing is going to close the breaker for us any more, so half-open
+		// when the check was due instead
+		if atomic.LoadInt64(&b.openUntil) == math.MaxInt64 {
+			atomic.StoreInt64(&b.openUntil, b.healthDue)
+		}
 	}
+}
 
-	// oh well, I guess we have to contend on the lock
-	b.processResult(result, panicValue)
+// permit records how a call was admitted by the breaker.
+type permit struct {
+	state State
+	// probe is true if the call holds one of the limited half-open slots, which
+	// must be released when it completes
+	probe      bool
+	generation uint64
+	// parent is the permit for the call from the parent breaker, if any
+	parent *permit
+}
+
+func (b *Breaker) admit() (permit, error) {
+	p, err := b.tryAdmit()
+	if err == nil && b.parent != nil {
+		var pp permit
+		if pp, err = b.parent.admit(); err == nil {
+			p.parent = &pp
+		} else if p.probe {
+			b.release(p)
+		}
+	}
+	if err != nil {
+		atomic.AddUint64(&b.counters.rejections, 1)
+	}
+	return p, err
+}
+
+func (b *Breaker) tryAdmit() (permit, error) {
+	state := b.GetState()
+
+	if state == Open || state == ForcedOpen {
+		return permit{state: state}, ErrBreakerOpen
+	}
+
+	if state != HalfOpen || b.halfOpenLimit <= 0 {
+		return permit{state: state}, nil
+	}
+
+	b.lock.Lock()
+	defer b.unlock()
+
+	// the state may have changed while we waited for the lock
+	b.refresh()
+	switch b.state {
+	case Open, ForcedOpen:
+		return permit{state: b.state}, ErrBreakerOpen
+	case HalfOpen:
+		if b.probes >= b.halfOpenLimit {
+			return permit{state: b.state}, ErrBreakerOpen
+		}
+		b.probes++
+		return permit{state: b.state, probe: true, generation: b.generation}, nil
+	default:
+		return permit{state: b.state}, nil
+	}
+}
+
+func (b *Breaker) release(p permit) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// if the state changed in the meantime then the slots were already reset
+	if p.generation == b.generation {
+		b.probes--
+	}
+}
+
+func (b *Breaker) doWork(ctx context.Context, p permit, work func() error) error {
+	result, panicErr := b.account(ctx, p, func() (result error, panicErr *PanicError) {
+		defer func() {
+			if val := recover(); val != nil {
+				// capture the stack here, while the panicking frames are still on it
+				panicErr = &PanicError{Value: val, Stack: debug.Stack()}
+			}
+		}()
+		return work(), nil
+	})
+
+	if panicErr != nil {
+		if b.recoverPanics {
+			return panicErr
+		}
 
-	if panicValue != nil {
 		// as close as Go lets us come to a "rethrow" although unfortunately
 		// we lose the original panicing location
-		panic(panicValue)
+		panic(panicErr.Value)
 	}
 
 	return result
 }
 
-func (b *Breaker) processResult(result error, panicValue interface{}) {
+// account runs the work function (which must not panic, but reports any panic it caught instead)
+// and accounts for its outcome, along with any parent breakers. The panic is passed back to the
+// caller rather than applying our panic policy, so that a child breaker's policy wins over its
+// parent's.
+func (b *Breaker) account(ctx context.Context, p permit, work func() (error, *PanicError)) (error, *PanicError) {
+	if p.probe {
+		defer b.release(p)
+	}
+
+	if p.parent != nil {
+		// the parent accounts for the call with its own policies, inside ours
+		parent, inner := b.parent, work
+		work = func() (error, *PanicError) {
+			return parent.account(ctx, *p.parent, inner)
+		}
+	}
+
+	state := p.state
+	var start time.Time
+
+	if b.slowThreshold > 0 {
+		start = b.clock.Now()
+	}
+
+	result, panicErr := work()
+
+	slow := b.slowThreshold > 0 && b.clock.Now().Sub(start) > b.slowThreshold
+	if slow {
+		atomic.AddUint64(&b.counters.slowCalls, 1)
+	}
+
+	if panicErr != nil {
+		atomic.AddUint64(&b.counters.failures, 1)
+
+		// panics always count as failures, regardless of classification
+		b.processResult(panicErr, slow)
+		return nil, panicErr
+	}
+
+	outcome := b.classify(ctx, result)
+	b.counters.record(outcome)
+
+	if state == ForcedClosed {
+		// nothing is counted while forced closed
+		return result, nil
+	}
+
+	switch outcome {
+	case success:
+		if state == Closed && b.window == nil && !slow {
+			// short-circuit the normal, success path without contending
+			// on the lock
+			return result, nil
+		}
+		b.processResult(nil, slow)
+	case failure:
+		// oh well, I guess we have to contend on the lock
+		b.processResult(result, slow)
+	case ignored:
+	}
+
+	return result, nil
+}
+
+// outcome is how the breaker accounts for the result of a single call.
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	ignored
+)
+
+func (b *Breaker) classify(ctx context.Context, result error) outcome {
+	if result != nil && !b.countContextErrors {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(result, ctxErr) {
+			// the caller gave up; that's not the dependency's fault
+			return ignored
+		}
+	}
+
+	switch b.class.Classify(result) {
+	case retrier.Succeed:
+		return success
+	case retrier.Retry:
+		return failure
+	default:
+		return ignored
+	}
+}
+
+func (b *Breaker) processResult(result error, slow bool) {
 	b.lock.Lock()
-	defer b.lock.Unlock()
+	defer b.unlock()
 
-	if result == nil && panicValue == nil {
-		if b.state == HalfOpen {
-			b.successes++
-			if b.successes == b.successThreshold {
-				b.closeBreaker()
-			}
+	if b.window != nil && b.state == Closed {
+		// the window is kept locally, so there is no need to involve the
+		// store unless the breaker actually opens
+		if trip, cause := b.processWindowedResult(result, slow); trip {
+			b.update(func() {
+				if b.state == Closed {
+					b.openBreaker(cause)
+				}
+			})
 		}
-	} else {
-		if b.errors > 0 {
-			expiry := b.lastError.Add(b.timeout)
-			if time.Now().After(expiry) {
-				b.errors = 0
+		return
+	}
+
+	b.update(func() {
+		if b.state == ForcedOpen || b.state == ForcedClosed {
+			// the state may have been forced while this call was running
+			return
+		}
+
+		if b.window != nil && b.state == Closed {
+			if trip, cause := b.processWindowedResult(result, slow); trip {
+				b.openBreaker(cause)
 			}
+			return
+		}
+
+		if result == nil && slow {
+			result = ErrSlowCall
 		}
 
-		switch b.state {
-		case Closed:
-			b.errors++
-			if b.errors == b.errorThreshold {
-				b.openBreaker()
-			} else {
-				b.lastError = time.Now()
+		if result == nil {
+			if b.state == HalfOpen {
+				b.successes++
+				if b.successes == b.successThreshold {
+					b.closeBreaker(nil)
+				}
 			}
-		case HalfOpen:
-			b.openBreaker()
+		} else {
+			if b.errors > 0 {
+				expiry := b.lastError.Add(b.timeout)
+				if b.clock.Now().After(expiry) {
+					b.errors = 0
+				}
+			}
+
+			switch b.state {
+			case Closed:
+				b.errors++
+				if b.errors == b.errorThreshold {
+					b.openBreaker(result)
+				} else {
+					b.lastError = b.clock.Now()
+				}
+			case HalfOpen:
+				b.openBreaker(result)
+			}
+		}
+	})
+}
+
+// processWindowedResult records the result in the window, and reports whether that tips it over the
+// threshold, along with the cause with which to open the breaker.
+func (b *Breaker) processWindowedResult(result error, slow bool) (bool, error) {
+	now := b.clock.Now()
+	b.windowLock.Lock()
+	b.window.record(now, result != nil, slow)
+	total, failures, slowCalls := b.window.counts(now)
+	b.windowLock.Unlock()
+
+	if total < b.minRequests || total == 0 {
+		return false, nil
+	}
+
+	if float64(failures)/float64(total) >= b.failureRate {
+		return true, result
+	} else if b.slowThreshold > 0 && float64(slowCalls)/float64(total) >= b.slowRate {
+		if result == nil {
+			result = ErrSlowCall
 		}
+		return true, result
 	}
+	return false, nil
 }
 
-func (b *Breaker) openBreaker() {
-	b.changeState(Open)
-	go b.timer()
+func (b *Breaker) openBreaker(cause error) {
+	b.changeState(Open, cause)
+
+	due := b.clock.Now().Add(b.openDuration()).UnixNano()
+	if b.healthCheck != nil && !b.closed {
+		// never half-open; the health check will close the breaker instead
+		b.awaitHealthCheck(due)
+	} else {
+		atomic.StoreInt64(&b.openUntil, due)
+	}
+
+	b.opens++
 }
 
-func (b *Breaker) closeBreaker() {
-	b.changeState(Closed)
+func (b *Breaker) closeBreaker(cause error) {
+	b.changeState(Closed, cause)
+	b.opens = 0
 }
 
-func (b *Breaker) timer() {
-	time.Sleep(b.timeout)
+func (b *Breaker) openDuration() time.Duration {
+	if len(b.openBackoff) == 0 {
+		return b.timeout
+	}
 
-	b.lock.Lock()
-	defer b.lock.Unlock()
+	i := b.opens
+	if i >= len(b.openBackoff) {
+		i = len(b.openBackoff) - 1
+	}
+	// take a random float in the range (-b.openJitter, +b.openJitter) and multiply it by the base amount
+	return b.openBackoff[i] + time.Duration(((b.rand.Float64()*2)-1)*b.openJitter*float64(b.openBackoff[i]))
+}
 
-	b.changeState(HalfOpen)
+// expireOpen moves the breaker from open to half-open if the timeout has passed.
+// It must be called with the lock held.
+func (b *Breaker) expireOpen() {
+	if b.state == Open && !b.clock.Now().Before(time.Unix(0, atomic.LoadInt64(&b.openUntil))) {
+		b.changeState(HalfOpen, nil)
+	}
 }
 
-func (b *Breaker) changeState(newState State) {
+func (b *Breaker) changeState(newState State, cause error) {
+	if b.healthTimer != nil {
+		b.healthTimer.Stop()
+		b.healthTimer = nil
+	}
+
+	if newState != b.state {
+		now := b.clock.Now()
+		b.events.notify(StateChange{
+			From: b.state,
+			To:   newState,
+			At:   now,
+			Err:  cause,
+		})
+		atomic.StoreInt64(&b.stateSince, now.UnixNano())
+	}
+
 	b.errors = 0
 	b.successes = 0
+	b.probes = 0
+	b.generation++
+	if b.window != nil {
+		b.windowLock.Lock()
+		b.window.reset()
+		b.windowLock.Unlock()
+	}
 	atomic.StoreUint32((*uint32)(&b.state), (uint32)(newState))
 }
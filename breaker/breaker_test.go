@@ -1,9 +1,15 @@
 package breaker
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+	"github.com/eapache/go-resiliency/retrier"
 )
 
 var errSomeError = errors.New("errSomeError")
@@ -21,7 +27,8 @@ func returnsSuccess() error {
 }
 
 func TestBreakerErrorExpiry(t *testing.T) {
-	breaker := New(2, 1, 10*time.Millisecond)
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(2, 1, 10*time.Millisecond).WithClock(c)
 	if breaker.GetState() != Closed {
 		t.Error("incorrect state")
 	}
@@ -30,7 +37,7 @@ func TestBreakerErrorExpiry(t *testing.T) {
 		if err := breaker.Run(returnsError); err != errSomeError {
 			t.Error(err)
 		}
-		time.Sleep(10 * time.Millisecond)
+		c.Advance(11 * time.Millisecond)
 	}
 	if breaker.GetState() != Closed {
 		t.Error("incorrect state")
@@ -40,7 +47,9 @@ func TestBreakerErrorExpiry(t *testing.T) {
 		if err := breaker.Go(returnsError); err != nil {
 			t.Error(err)
 		}
-		time.Sleep(10 * time.Millisecond)
+		// just enough to yield the scheduler and let the goroutines work off
+		time.Sleep(1 * time.Millisecond)
+		c.Advance(11 * time.Millisecond)
 	}
 	if breaker.GetState() != Closed {
 		t.Error("incorrect state")
@@ -80,8 +89,44 @@ func TestBreakerPanicsCountAsErrors(t *testing.T) {
 	}
 }
 
+func TestBreakerPanicRecovery(t *testing.T) {
+	breaker := New(2, 1, 1*time.Second).WithPanicRecovery()
+
+	err := breaker.Run(alwaysPanics)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "foo" {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(panicErr.Stack), "alwaysPanics") {
+		t.Error("stack trace does not include the panicking function")
+	}
+	if err.Error() != "circuit breaker: work function panicked: foo" {
+		t.Error(err)
+	}
+
+	// panics in Go are recovered too, and still count as failures
+	if err := breaker.Go(alwaysPanics); err != nil {
+		t.Error(err)
+	}
+	// just enough to yield the scheduler and let the goroutines work off
+	time.Sleep(1 * time.Millisecond)
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// error values are unwrapped
+	breaker = New(2, 1, 1*time.Second).WithPanicRecovery()
+	err = breaker.Run(func() error {
+		panic(errSomeError)
+	})
+	if !errors.Is(err, errSomeError) {
+		t.Error(err)
+	}
+}
+
 func TestBreakerStateTransitions(t *testing.T) {
-	breaker := New(3, 2, 10*time.Millisecond)
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(3, 2, 10*time.Millisecond).WithClock(c)
 	if breaker.GetState() != Closed {
 		t.Error("incorrect state")
 	}
@@ -104,7 +149,7 @@ func TestBreakerStateTransitions(t *testing.T) {
 	}
 
 	// wait for it to half-close
-	time.Sleep(20 * time.Millisecond)
+	c.Advance(20 * time.Millisecond)
 	if breaker.GetState() != HalfOpen {
 		t.Error("incorrect state")
 	}
@@ -125,7 +170,7 @@ func TestBreakerStateTransitions(t *testing.T) {
 	}
 
 	// wait for it to half-close
-	time.Sleep(20 * time.Millisecond)
+	c.Advance(20 * time.Millisecond)
 	if breaker.GetState() != HalfOpen {
 		t.Error("incorrect state")
 	}
@@ -152,7 +197,8 @@ func TestBreakerStateTransitions(t *testing.T) {
 }
 
 func TestBreakerAsyncStateTransitions(t *testing.T) {
-	breaker := New(3, 2, 10*time.Millisecond)
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(3, 2, 10*time.Millisecond).WithClock(c)
 
 	// three errors opens the breaker
 	for i := 0; i < 3; i++ {
@@ -172,7 +218,7 @@ func TestBreakerAsyncStateTransitions(t *testing.T) {
 	}
 
 	// wait for it to half-close
-	time.Sleep(20 * time.Millisecond)
+	c.Advance(20 * time.Millisecond)
 	// one success works, but is not enough to fully close
 	if err := breaker.Go(returnsSuccess); err != nil {
 		t.Error(err)
@@ -189,7 +235,7 @@ func TestBreakerAsyncStateTransitions(t *testing.T) {
 	}
 
 	// wait for it to half-close
-	time.Sleep(20 * time.Millisecond)
+	c.Advance(20 * time.Millisecond)
 	// two successes is enough to close it for good
 	for i := 0; i < 2; i++ {
 		if err := breaker.Go(returnsSuccess); err != nil {
@@ -210,6 +256,583 @@ func TestBreakerAsyncStateTransitions(t *testing.T) {
 	}
 }
 
+func TestBreakerFailureRate(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithFailureRate(0.5, 4, time.Hour, 10)
+
+	// a single error does not open the breaker without enough volume
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// nor does a failure rate below the threshold
+	for i := 0; i < 3; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// reaching the threshold opens it
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	// wait for it to half-close, then close it with a single success
+	c.Advance(20 * time.Millisecond)
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// the window was reset when the breaker closed
+	for i := 0; i < 3; i++ {
+		if err := breaker.Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerCountedFailureRate(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithCountedFailureRate(0.5, 2, 4)
+
+	// one success and one error is exactly the threshold
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// wait for it to half-close, then close it with a single success
+	c.Advance(20 * time.Millisecond)
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// old errors fall out of the window as new calls are recorded
+	for i := 0; i < 10; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+		if i%4 == 3 {
+			if err := breaker.Run(returnsError); err != errSomeError {
+				t.Error(err)
+			}
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerRunCtx(t *testing.T) {
+	breaker := New(1, 1, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the context is passed through to the work function
+	if err := breaker.RunCtx(ctx, func(c context.Context) error {
+		if c != ctx {
+			t.Error("incorrect context")
+		}
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// errors caused by the caller's context are not counted
+	if err := breaker.RunCtx(ctx, func(c context.Context) error {
+		cancel()
+		return fmt.Errorf("wrapped: %w", c.Err())
+	}); !errors.Is(err, context.Canceled) {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// a done context refuses to run the work at all
+	if err := breaker.RunCtx(ctx, func(c context.Context) error {
+		t.Error("shouldn't get here")
+		return nil
+	}); err != context.Canceled {
+		t.Error(err)
+	}
+
+	// context errors not caused by the caller's own context still count
+	if err := breaker.RunCtx(context.Background(), func(c context.Context) error {
+		return context.DeadlineExceeded
+	}); err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	if err := breaker.RunCtx(context.Background(), func(c context.Context) error {
+		t.Error("shouldn't get here")
+		return nil
+	}); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+}
+
+func TestBreakerCountContextErrors(t *testing.T) {
+	breaker := New(1, 1, 10*time.Millisecond).WithCountContextErrors()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := breaker.RunCtx(ctx, func(c context.Context) error {
+		cancel()
+		return c.Err()
+	}); err != context.Canceled {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerSlowCalls(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	returnsSlowly := func() error {
+		c.Advance(5 * time.Millisecond)
+		return nil
+	}
+	breaker := New(2, 1, 10*time.Millisecond).WithClock(c).WithSlowCallThreshold(time.Millisecond, 1)
+
+	// fast calls don't count
+	for i := 0; i < 3; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// without a window each slow call counts as an error
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsSlowly); err != nil {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// a slow call re-opens the breaker when half-open
+	c.Advance(20 * time.Millisecond)
+	if err := breaker.Run(returnsSlowly); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerSlowCallRate(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	returnsSlowly := func() error {
+		c.Advance(5 * time.Millisecond)
+		return nil
+	}
+	breaker := New(0, 1, 10*time.Millisecond).WithClock(c).
+		WithCountedFailureRate(0.5, 4, 4).
+		WithSlowCallThreshold(time.Millisecond, 0.5)
+
+	if err := breaker.Run(returnsSlowly); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// two of four calls slow reaches the threshold
+	if err := breaker.Run(returnsSlowly); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerHalfOpenLimit(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	returnsSlowly := func() error {
+		c.Advance(5 * time.Millisecond)
+		return nil
+	}
+	breaker := New(1, 2, 10*time.Millisecond).WithClock(c).WithHalfOpenLimit(1)
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(20 * time.Millisecond)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+
+	// the first call takes the only trial slot
+	started, finish, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	if err := breaker.Go(func() error {
+		defer close(done)
+		close(started)
+		<-finish
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+	<-started
+
+	// so other callers are rejected until it completes
+	for i := 0; i < 3; i++ {
+		if err := breaker.Run(returnsSuccess); err != ErrBreakerOpen {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+
+	close(finish)
+	<-done
+	time.Sleep(1 * time.Millisecond)
+
+	// the slot is free again, and a second success closes the breaker
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+	for i := 0; i < 3; i++ {
+		if err := breaker.Go(returnsSlowly); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBreakerOpenBackoff(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 1*time.Hour).WithClock(c).WithOpenBackoff(retrier.ExponentialBackoff(2, 10*time.Millisecond), 0)
+
+	if breaker.openDuration() != 10*time.Millisecond {
+		t.Error("incorrect open duration")
+	}
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(15 * time.Millisecond)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+
+	// a failed trial doubles the time spent open
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(15 * time.Millisecond)
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	c.Advance(15 * time.Millisecond)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+
+	// the last value is re-used once the backoff is exhausted
+	if breaker.openDuration() != 20*time.Millisecond {
+		t.Error("incorrect open duration")
+	}
+
+	// and it resets once the breaker closes
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.openDuration() != 10*time.Millisecond {
+		t.Error("incorrect open duration")
+	}
+}
+
+func TestBreakerOpenBackoffJitter(t *testing.T) {
+	breaker := New(1, 1, 1*time.Hour).WithOpenBackoff([]time.Duration{4 * time.Hour}, 0.25)
+
+	for i := 0; i < 20; i++ {
+		d := breaker.openDuration()
+		if d < 3*time.Hour || d > 5*time.Hour {
+			t.Error("incorrect open duration", d)
+		}
+	}
+
+	breaker = New(1, 1, 1*time.Hour).WithOpenBackoff([]time.Duration{4 * time.Hour}, 2)
+	if breaker.openDuration() != 4*time.Hour {
+		t.Error("invalid jitter value accepted")
+	}
+}
+
+func TestBreakerForceOpen(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c)
+
+	// open the breaker normally, so there is a pending transition to half-open
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	breaker.ForceOpen()
+	if breaker.GetState() != ForcedOpen {
+		t.Error("incorrect state")
+	}
+
+	// the pending transition must not release the override
+	c.Advance(time.Hour)
+	if breaker.GetState() != ForcedOpen {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	if err := breaker.Go(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	breaker.Reset()
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBreakerForceClosed(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c)
+
+	breaker.ForceClosed()
+	if breaker.GetState() != ForcedClosed {
+		t.Error("incorrect state")
+	}
+
+	// errors are passed along but not counted
+	for i := 0; i < 5; i++ {
+		if err := breaker.Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != ForcedClosed {
+		t.Error("incorrect state")
+	}
+
+	breaker.Reset()
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// reset also cancels the pending transition to half-open
+	breaker.Reset()
+	c.Advance(time.Hour)
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerNoTimerGoroutines(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c)
+
+	// opening the breaker does not schedule anything to happen later
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	c.Advance(9 * time.Millisecond)
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// the transition to half-open is computed when next checked
+	c.Advance(1 * time.Millisecond)
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerGoResult(t *testing.T) {
+	breaker := New(1, 1, 1*time.Hour).WithPanicRecovery()
+
+	result, err := breaker.GoResult(returnsSuccess)
+	if err != nil {
+		t.Error(err)
+	}
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-result; ok {
+		t.Error("result channel not closed")
+	}
+
+	result, err = breaker.GoResult(alwaysPanics)
+	if err != nil {
+		t.Error(err)
+	}
+	var panicErr *PanicError
+	if err := <-result; !errors.As(err, &panicErr) {
+		t.Error(err)
+	}
+
+	result, err = breaker.GoResult(returnsSuccess)
+	if result != nil || err != ErrBreakerOpen {
+		t.Error(result, err)
+	}
+}
+
+func TestBreakerGoCallback(t *testing.T) {
+	breaker := New(1, 1, 1*time.Hour)
+
+	results := make(chan error, 1)
+	done := func(err error) {
+		results <- err
+	}
+
+	if err := breaker.GoCallback(returnsError, done); err != nil {
+		t.Error(err)
+	}
+	if err := <-results; err != errSomeError {
+		t.Error(err)
+	}
+
+	if err := breaker.GoCallback(returnsSuccess, done); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	select {
+	case err := <-results:
+		t.Error("callback called unexpectedly", err)
+	case <-time.After(5 * time.Millisecond):
+	}
+}
+
+type notFoundClassifier struct{}
+
+var errNotFound = errors.New("errNotFound")
+
+func (notFoundClassifier) Classify(err error) retrier.Action {
+	if err == nil || err == errNotFound {
+		return retrier.Succeed
+	}
+	return retrier.Retry
+}
+
+func TestBreakerClassifier(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(2, 1, 1*time.Second).WithClock(c).WithClassifier(retrier.WhitelistClassifier{errSomeError})
+
+	// errors classified as Fail are passed along but ignored
+	errOther := errors.New("errOther")
+	for i := 0; i < 5; i++ {
+		if err := breaker.Run(func() error { return errOther }); err != errOther {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+
+	// errors classified as Retry count as failures
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+
+	// errors classified as Succeed count as successes
+	breaker = New(1, 1, 10*time.Millisecond).WithClock(c).WithClassifier(notFoundClassifier{})
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(20 * time.Millisecond)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(func() error { return errNotFound }); err != errNotFound {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func TestExecute(t *testing.T) {
+	breaker := New(1, 1, 1*time.Second)
+
+	val, err := Execute(breaker, func() (string, error) {
+		return "foo", nil
+	})
+	if val != "foo" || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = ExecuteCtx(context.Background(), breaker, func(ctx context.Context) (string, error) {
+		return "bar", errSomeError
+	})
+	if val != "bar" || err != errSomeError {
+		t.Error(val, err)
+	}
+
+	val, err = Execute(breaker, func() (string, error) {
+		t.Error("shouldn't get here")
+		return "baz", nil
+	})
+	if val != "" || err != ErrBreakerOpen {
+		t.Error(val, err)
+	}
+}
+
 func ExampleBreaker() {
 	breaker := New(3, 1, 5*time.Second)
 
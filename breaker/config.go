@@ -0,0 +1,108 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eapache/go-resiliency/config"
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+// Config describes a Breaker in a form which can be loaded from a configuration file, for use with
+// FromConfig. The optional fields correspond to the With... methods of the same names, and are left
+// unconfigured if omitted.
+type Config struct {
+	ErrorThreshold   int             `json:"errorThreshold"`
+	SuccessThreshold int             `json:"successThreshold"`
+	Timeout          config.Duration `json:"timeout"`
+
+	FailureRate *FailureRateConfig     `json:"failureRate,omitempty"`
+	OpenBackoff *retrier.BackoffConfig `json:"openBackoff,omitempty"`
+	OpenJitter  float64                `json:"openJitter,omitempty"`
+
+	HalfOpenLimit     int             `json:"halfOpenLimit,omitempty"`
+	SlowCallThreshold config.Duration `json:"slowCallThreshold,omitempty"`
+	SlowCallRate      float64         `json:"slowCallRate,omitempty"`
+
+	PanicRecovery      bool `json:"panicRecovery,omitempty"`
+	CountContextErrors bool `json:"countContextErrors,omitempty"`
+}
+
+// FailureRateConfig describes the failure rate tracked by a Breaker. Exactly one of Window (see
+// WithFailureRate) or Size (see WithCountedFailureRate) must be set.
+type FailureRateConfig struct {
+	Rate            float64         `json:"rate"`
+	MinimumRequests int             `json:"minimumRequests"`
+	Window          config.Duration `json:"window,omitempty"`
+	Buckets         int             `json:"buckets,omitempty"`
+	Size            int             `json:"size,omitempty"`
+}
+
+// FromConfig constructs a Breaker as described by the given configuration. It returns an error if
+// the configuration is invalid: the success threshold must be at least 1, as must the error threshold
+// unless a failure rate is configured instead, the timeout must be positive, counts must not be
+// negative, rates must be between 0.0 and 1.0, and jitter requires an open back-off.
+func FromConfig(c Config) (*Breaker, error) {
+	if c.SuccessThreshold < 1 {
+		return nil, fmt.Errorf("circuit breaker: invalid success threshold %d", c.SuccessThreshold)
+	}
+	if c.FailureRate == nil && c.ErrorThreshold < 1 {
+		return nil, fmt.Errorf("circuit breaker: invalid error threshold %d", c.ErrorThreshold)
+	}
+	if c.Timeout <= 0 {
+		return nil, fmt.Errorf("circuit breaker: invalid timeout %v", time.Duration(c.Timeout))
+	}
+
+	b := New(c.ErrorThreshold, c.SuccessThreshold, time.Duration(c.Timeout))
+
+	if rate := c.FailureRate; rate != nil {
+		switch {
+		case rate.Rate < 0 || rate.Rate > 1:
+			return nil, fmt.Errorf("circuit breaker: failure rate %v is outside the range 0.0 to 1.0", rate.Rate)
+		case rate.MinimumRequests < 0:
+			return nil, fmt.Errorf("circuit breaker: invalid minimum requests %d", rate.MinimumRequests)
+		case rate.Buckets < 0:
+			return nil, fmt.Errorf("circuit breaker: invalid number of buckets %d", rate.Buckets)
+		case rate.Window > 0 && rate.Size > 0:
+			return nil, errors.New("circuit breaker: failure rate must have a window or a size, not both")
+		case rate.Window > 0:
+			b.WithFailureRate(rate.Rate, rate.MinimumRequests, time.Duration(rate.Window), rate.Buckets)
+		case rate.Size > 0:
+			b.WithCountedFailureRate(rate.Rate, rate.MinimumRequests, rate.Size)
+		default:
+			return nil, errors.New("circuit breaker: failure rate must have a window or a size")
+		}
+	}
+
+	if c.OpenBackoff != nil {
+		if c.OpenJitter < 0 || c.OpenJitter > 1 {
+			return nil, fmt.Errorf("circuit breaker: jitter %v is outside the range 0.0 to 1.0", c.OpenJitter)
+		}
+		backoff, err := c.OpenBackoff.Backoff()
+		if err != nil {
+			return nil, err
+		}
+		b.WithOpenBackoff(backoff, c.OpenJitter)
+	} else if c.OpenJitter != 0 {
+		return nil, errors.New("circuit breaker: open jitter requires an open back-off")
+	}
+
+	if c.HalfOpenLimit > 0 {
+		b.WithHalfOpenLimit(c.HalfOpenLimit)
+	}
+	if c.SlowCallThreshold > 0 {
+		if c.SlowCallRate < 0 || c.SlowCallRate > 1 {
+			return nil, fmt.Errorf("circuit breaker: slow-call rate %v is outside the range 0.0 to 1.0", c.SlowCallRate)
+		}
+		b.WithSlowCallThreshold(time.Duration(c.SlowCallThreshold), c.SlowCallRate)
+	}
+	if c.PanicRecovery {
+		b.WithPanicRecovery()
+	}
+	if c.CountContextErrors {
+		b.WithCountContextErrors()
+	}
+
+	return b, nil
+}
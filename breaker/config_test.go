@@ -0,0 +1,77 @@
+package breaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+func TestFromConfig(t *testing.T) {
+	var c Config
+	err := json.Unmarshal([]byte(`{
+		"errorThreshold": 0,
+		"successThreshold": 2,
+		"timeout": "5s",
+		"failureRate": {"rate": 0.5, "minimumRequests": 20, "window": "10s", "buckets": 10},
+		"openBackoff": {"strategy": "exponential", "retries": 3, "initial": "1s"},
+		"halfOpenLimit": 1,
+		"slowCallThreshold": "200ms",
+		"slowCallRate": 0.8,
+		"panicRecovery": true
+	}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := FromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.successThreshold != 2 || b.timeout != 5*time.Second {
+		t.Error("incorrect thresholds")
+	}
+	if _, ok := b.window.(*timeWindow); !ok || b.failureRate != 0.5 || b.minRequests != 20 {
+		t.Error("incorrect failure rate")
+	}
+	if len(b.openBackoff) != 3 || b.openBackoff[2] != 4*time.Second {
+		t.Error("incorrect open backoff")
+	}
+	if b.halfOpenLimit != 1 || b.slowThreshold != 200*time.Millisecond || b.slowRate != 0.8 {
+		t.Error("incorrect limits")
+	}
+	if !b.recoverPanics || b.countContextErrors {
+		t.Error("incorrect options")
+	}
+
+	b, err = FromConfig(Config{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 0.5, Size: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.window.(*countWindow); !ok {
+		t.Error("incorrect failure rate")
+	}
+
+	invalid := []Config{
+		{ErrorThreshold: 1, Timeout: 1},
+		{SuccessThreshold: 1, Timeout: 1},
+		{ErrorThreshold: 1, SuccessThreshold: 1},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: -1},
+		{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 0.5}},
+		{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 0.5, Window: 1, Size: 1}},
+		{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 1.5, Size: 1}},
+		{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 0.5, MinimumRequests: -1, Size: 1}},
+		{SuccessThreshold: 1, Timeout: 1, FailureRate: &FailureRateConfig{Rate: 0.5, Window: 1, Buckets: -1}},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: 1, OpenBackoff: &retrier.BackoffConfig{Strategy: "fibonacci"}},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: 1, OpenBackoff: &retrier.BackoffConfig{Strategy: "constant"}, OpenJitter: -1},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: 1, OpenBackoff: &retrier.BackoffConfig{Strategy: "constant", Retries: -1}},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: 1, OpenJitter: 0.5},
+		{ErrorThreshold: 1, SuccessThreshold: 1, Timeout: 1, SlowCallThreshold: 1, SlowCallRate: 2},
+	}
+	for i, c := range invalid {
+		if _, err := FromConfig(c); err == nil {
+			t.Error("expected error for", i)
+		}
+	}
+}
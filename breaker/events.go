@@ -0,0 +1,89 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// StateChange describes a single transition of a Breaker between states.
+type StateChange struct {
+	From, To State
+	// At is the time at which the transition occurred.
+	At time.Time
+	// Err is the error which triggered the transition, or nil if the transition
+	// was not caused by an error (for example, the timeout expiring).
+	Err error
+}
+
+// maxPendingEvents bounds the number of StateChange events queued for delivery;
+// beyond it the oldest events are dropped.
+const maxPendingEvents = 1024
+
+// notifier delivers StateChange events to listeners in the order they occurred.
+// Events are queued without blocking and delivered from a separate goroutine,
+// which only runs while there are events pending, so that slow listeners cannot
+// hold up the breaker. If the listeners fall too far behind, the oldest
+// undelivered events are dropped so that the queue cannot grow without bound.
+type notifier struct {
+	lock      sync.Mutex
+	listeners []func(StateChange)
+	pending   []StateChange
+	running   bool
+	closed    bool
+}
+
+func (n *notifier) subscribe(fn func(StateChange)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// copy-on-write so that the dispatcher can iterate without holding the lock
+	listeners := make([]func(StateChange), len(n.listeners), len(n.listeners)+1)
+	copy(listeners, n.listeners)
+	n.listeners = append(listeners, fn)
+}
+
+func (n *notifier) notify(event StateChange) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if len(n.listeners) == 0 || n.closed {
+		return
+	}
+
+	if len(n.pending) >= maxPendingEvents {
+		n.pending = n.pending[1:]
+	}
+	n.pending = append(n.pending, event)
+	if !n.running {
+		n.running = true
+		go n.dispatch()
+	}
+}
+
+func (n *notifier) close() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.closed = true
+	n.pending = nil
+}
+
+func (n *notifier) dispatch() {
+	for {
+		n.lock.Lock()
+		if len(n.pending) == 0 {
+			n.pending = nil
+			n.running = false
+			n.lock.Unlock()
+			return
+		}
+		event := n.pending[0]
+		n.pending = n.pending[1:]
+		listeners := n.listeners
+		n.lock.Unlock()
+
+		for _, fn := range listeners {
+			fn(event)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestBreakerStateChangeEvents(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(2, 1, 10*time.Millisecond).WithClock(c)
+
+	events := make(chan StateChange, 10)
+	breaker.OnStateChange(func(ev StateChange) {
+		events <- ev
+	})
+
+	// a listener that blocks must not hold up the breaker or other listeners
+	block := make(chan struct{})
+	defer close(block)
+	breaker.OnStateChange(func(ev StateChange) {
+		<-block
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+	c.Advance(20 * time.Millisecond)
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+
+	expected := []StateChange{
+		{From: Closed, To: Open, Err: errSomeError},
+		{From: Open, To: HalfOpen},
+		{From: HalfOpen, To: Closed},
+	}
+	select {
+	case ev := <-events:
+		if ev.From != expected[0].From || ev.To != expected[0].To || ev.Err != expected[0].Err {
+			t.Error("incorrect event", ev)
+		}
+		if ev.At.IsZero() {
+			t.Error("missing event time")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	// the remaining events are queued behind the blocked listener
+	select {
+	case ev := <-events:
+		t.Error("unexpected event", ev)
+	case <-time.After(5 * time.Millisecond):
+	}
+	for i := 1; i < len(expected); i++ {
+		block <- struct{}{}
+		select {
+		case ev := <-events:
+			if ev.From != expected[i].From || ev.To != expected[i].To || ev.Err != expected[i].Err {
+				t.Error("incorrect event", ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
+
+func TestBreakerClose(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c)
+
+	events := make(chan StateChange, 10)
+	breaker.OnStateChange(func(ev StateChange) {
+		events <- ev
+	})
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	<-events
+
+	breaker.Close()
+	breaker.Close()
+
+	// the breaker still works, but no more events are delivered
+	c.Advance(20 * time.Millisecond)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+	select {
+	case ev := <-events:
+		t.Error("unexpected event", ev)
+	case <-time.After(5 * time.Millisecond):
+	}
+}
+
+func TestNotifierDropsOldest(t *testing.T) {
+	var n notifier
+	blocked, block := make(chan struct{}), make(chan struct{})
+	events := make(chan StateChange, 2*maxPendingEvents)
+	n.subscribe(func(ev StateChange) {
+		if ev.At.IsZero() {
+			close(blocked)
+			<-block
+		}
+		events <- ev
+	})
+
+	// the first event blocks the listener while the rest overflow the queue
+	n.notify(StateChange{})
+	<-blocked
+	total := maxPendingEvents + 10
+	for i := 1; i <= total; i++ {
+		n.notify(StateChange{At: time.Unix(int64(i), 0)})
+	}
+	close(block)
+
+	<-events
+	for i := total - maxPendingEvents + 1; i <= total; i++ {
+		select {
+		case ev := <-events:
+			if !ev.At.Equal(time.Unix(int64(i), 0)) {
+				t.Fatal("incorrect event", i, ev.At)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Error("unexpected event", ev)
+	case <-time.After(5 * time.Millisecond):
+	}
+}
+
+func TestStateString(t *testing.T) {
+	if Closed.String() != "closed" || Open.String() != "open" || HalfOpen.String() != "half-open" {
+		t.Error("incorrect state names")
+	}
+	if ForcedOpen.String() != "forced-open" || ForcedClosed.String() != "forced-closed" {
+		t.Error("incorrect forced state names")
+	}
+	if State(42).String() != "State(42)" {
+		t.Error("incorrect unknown state name")
+	}
+}
@@ -0,0 +1,67 @@
+package breaker
+
+import "fmt"
+
+// FallbackError is returned from RunWithFallback and RunWithFailureFallback when the fallback
+// function fails, so that its failure can be distinguished from the failure which caused the
+// fallback to be invoked in the first place.
+type FallbackError struct {
+	// Reason is the error that caused the fallback to be invoked: either ErrBreakerOpen, or the
+	// error returned by the work function.
+	Reason error
+	// Err is the error returned by the fallback function, or nil if it panicked.
+	Err error
+	// Panic is the value the fallback function panicked with, or nil if it did not panic.
+	Panic interface{}
+}
+
+// Error implements the error interface.
+func (e *FallbackError) Error() string {
+	if e.Panic != nil {
+		return fmt.Sprintf("circuit breaker fallback panicked: %v (fallback reason: %v)", e.Panic, e.Reason)
+	}
+	return fmt.Sprintf("circuit breaker fallback failed: %v (fallback reason: %v)", e.Err, e.Reason)
+}
+
+// Unwrap returns the error returned by the fallback function.
+func (e *FallbackError) Unwrap() error {
+	return e.Err
+}
+
+// RunWithFallback is like Run, except that if the breaker is open then instead of returning
+// ErrBreakerOpen it calls the fallback function with ErrBreakerOpen as the reason, for example to
+// serve a cached or default value. If the fallback returns nil (or is not called) then the result
+// is the same as for Run. If the fallback returns an error or panics, a *FallbackError is returned.
+func (b *Breaker) RunWithFallback(work func() error, fallback func(reason error) error) error {
+	return b.runWithFallback(work, fallback, false)
+}
+
+// RunWithFailureFallback is like RunWithFallback, except that the fallback function is also called
+// when the work function returns an error, with that error as the reason. The work function's error
+// is still accounted for by the breaker as usual.
+func (b *Breaker) RunWithFailureFallback(work func() error, fallback func(reason error) error) error {
+	return b.runWithFallback(work, fallback, true)
+}
+
+func (b *Breaker) runWithFallback(work func() error, fallback func(error) error, onFailure bool) error {
+	err := b.Run(work)
+
+	if err == nil || (err != ErrBreakerOpen && !onFailure) {
+		return err
+	}
+
+	return callFallback(fallback, err)
+}
+
+func callFallback(fallback func(error) error, reason error) (err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			err = &FallbackError{Reason: reason, Panic: val}
+		}
+	}()
+
+	if fbErr := fallback(reason); fbErr != nil {
+		return &FallbackError{Reason: reason, Err: fbErr}
+	}
+	return nil
+}
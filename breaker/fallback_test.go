@@ -0,0 +1,97 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerRunWithFallback(t *testing.T) {
+	breaker := New(1, 1, 1*time.Hour)
+
+	var reasons []error
+	fallback := func(reason error) error {
+		reasons = append(reasons, reason)
+		return nil
+	}
+
+	// work failures are passed along without falling back
+	if err := breaker.RunWithFallback(returnsError, fallback); err != errSomeError {
+		t.Error(err)
+	}
+	if len(reasons) != 0 {
+		t.Error("fallback called unexpectedly")
+	}
+
+	// an open breaker falls back
+	if err := breaker.RunWithFallback(returnsSuccess, fallback); err != nil {
+		t.Error(err)
+	}
+	if len(reasons) != 1 || reasons[0] != ErrBreakerOpen {
+		t.Error("incorrect fallback reasons", reasons)
+	}
+}
+
+func TestBreakerRunWithFailureFallback(t *testing.T) {
+	breaker := New(2, 1, 1*time.Hour)
+
+	var reasons []error
+	fallback := func(reason error) error {
+		reasons = append(reasons, reason)
+		return nil
+	}
+
+	if err := breaker.RunWithFailureFallback(returnsSuccess, fallback); err != nil {
+		t.Error(err)
+	}
+	if err := breaker.RunWithFailureFallback(returnsError, fallback); err != nil {
+		t.Error(err)
+	}
+	if err := breaker.RunWithFailureFallback(returnsError, fallback); err != nil {
+		t.Error(err)
+	}
+	// the failures still count against the breaker
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	if err := breaker.RunWithFailureFallback(returnsSuccess, fallback); err != nil {
+		t.Error(err)
+	}
+
+	expected := []error{errSomeError, errSomeError, ErrBreakerOpen}
+	if len(reasons) != len(expected) {
+		t.Fatal("incorrect fallback reasons", reasons)
+	}
+	for i := range expected {
+		if reasons[i] != expected[i] {
+			t.Error("incorrect fallback reason", reasons[i])
+		}
+	}
+}
+
+func TestBreakerFallbackErrors(t *testing.T) {
+	breaker := New(1, 1, 1*time.Hour)
+	breaker.ForceOpen()
+
+	errFallback := errors.New("errFallback")
+	err := breaker.RunWithFallback(returnsSuccess, func(reason error) error {
+		return errFallback
+	})
+	var fbErr *FallbackError
+	if !errors.As(err, &fbErr) || fbErr.Reason != ErrBreakerOpen || fbErr.Err != errFallback || fbErr.Panic != nil {
+		t.Error(err)
+	}
+	if !errors.Is(err, errFallback) {
+		t.Error("fallback error not unwrapped")
+	}
+
+	err = breaker.RunWithFallback(returnsSuccess, func(reason error) error {
+		panic("foo")
+	})
+	if !errors.As(err, &fbErr) || fbErr.Reason != ErrBreakerOpen || fbErr.Err != nil || fbErr.Panic != "foo" {
+		t.Error(err)
+	}
+	if err.Error() != "circuit breaker fallback panicked: foo (fallback reason: circuit breaker is open)" {
+		t.Error(err)
+	}
+}
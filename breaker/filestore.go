@@ -0,0 +1,82 @@
+package breaker
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// FileStore is a Store which keeps the snapshot as JSON in a file, so that it can be shared by
+// breakers in different processes on the same machine. Updates are serialized with an advisory lock
+// on the file where the platform supports it; elsewhere they are only serialized within a single
+// process.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore constructs a new FileStore using the file at the given path, which is created on
+// first update if it does not exist. A missing or empty file holds a closed breaker.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements Store.
+func (f *FileStore) Load() (Snapshot, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return Snapshot{}, nil
+	} else if err != nil {
+		return Snapshot{}, err
+	}
+	defer file.Close()
+
+	if err := lockFile(file, false); err != nil {
+		return Snapshot{}, err
+	}
+	defer unlockFile(file)
+
+	return readSnapshot(file)
+}
+
+// Update implements Store.
+func (f *FileStore) Update(fn func(*Snapshot)) error {
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := lockFile(file, true); err != nil {
+		return err
+	}
+	defer unlockFile(file)
+
+	s, err := readSnapshot(file)
+	if err != nil {
+		return err
+	}
+
+	fn(&s)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(data, 0)
+	return err
+}
+
+func readSnapshot(file *os.File) (Snapshot, error) {
+	var s Snapshot
+
+	data, err := io.ReadAll(file)
+	if err != nil || len(data) == 0 {
+		return s, err
+	}
+
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package breaker
+
+import (
+	"os"
+	"sync"
+)
+
+// there is no file lock available here, so fall back to serializing access
+// within this process
+var fileLock sync.Mutex
+
+func lockFile(file *os.File, exclusive bool) error {
+	fileLock.Lock()
+	return nil
+}
+
+func unlockFile(file *os.File) {
+	fileLock.Unlock()
+}
@@ -0,0 +1,66 @@
+package breaker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breaker.json")
+	store := NewFileStore(path)
+
+	s, err := store.Load()
+	if err != nil || s.State != Closed {
+		t.Error(s, err)
+	}
+
+	now := time.Unix(100, 0)
+	err = store.Update(func(s *Snapshot) {
+		s.State = Open
+		s.OpenUntil = now
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	s, err = NewFileStore(path).Load()
+	if err != nil || s.State != Open || !s.OpenUntil.Equal(now) {
+		t.Error(s, err)
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBreakerFileStore(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	path := filepath.Join(t.TempDir(), "breaker.json")
+	a := New(1, 1, 10*time.Millisecond).WithClock(c).WithStore(NewFileStore(path), nil)
+	b := New(1, 1, 10*time.Millisecond).WithClock(c).WithStore(NewFileStore(path), nil)
+
+	if err := a.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := b.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	c.Advance(10 * time.Millisecond)
+	if b.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+	if err := b.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if a.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"math"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// WithHealthCheck configures the breaker to verify that the dependency has recovered by itself,
+// rather than letting real calls through to find out. Once the open timeout (or back-off, see
+// WithOpenBackoff) has passed, the breaker calls "check" from a separate goroutine, and then again
+// after each further timeout (the duration passed to New) up to "successes" times in total, so that
+// the dependency has to stay healthy for a while. If every call returns nil the breaker closes
+// directly; if any returns an error (or panics) the breaker stays open and starts again after the
+// next open timeout. The breaker never half-opens in this mode, so no calls reach the dependency
+// until it has been verified healthy. Call Close to cancel any pending health check when the breaker
+// is no longer needed. It must be called before the breaker is used.
+func (b *Breaker) WithHealthCheck(check func() error, successes int) *Breaker {
+	if successes < 1 {
+		successes = 1
+	}
+	b.healthCheck = check
+	b.healthSuccesses = successes
+	return b
+}
+
+// awaitHealthCheck holds the breaker open, rather than letting it half-open, until the health check
+// due at the given time (in UnixNano) has run. It must be called with the lock held.
+func (b *Breaker) awaitHealthCheck(due int64) {
+	b.healthDue = due
+	atomic.StoreInt64(&b.openUntil, math.MaxInt64)
+
+	generation := b.generation
+	b.healthTimer = b.clock.AfterFunc(time.Unix(0, due).Sub(b.clock.Now()), func() {
+		b.runHealthCheck(generation, 0)
+	})
+}
+
+func (b *Breaker) runHealthCheck(generation uint64, passed int) {
+	err := b.callHealthCheck()
+
+	b.lock.Lock()
+	defer b.unlock()
+
+	b.update(func() {
+		if b.generation != generation {
+			// the state changed in the meantime (e.g. it was forced), so the
+			// result is stale
+			return
+		}
+
+		switch {
+		case err != nil:
+			b.openBreaker(err)
+		case passed+1 < b.healthSuccesses:
+			if b.closed {
+				// nothing is going to check again; Close has already arranged
+				// for the breaker to half-open instead
+				return
+			}
+			b.healthTimer = b.clock.AfterFunc(b.timeout, func() {
+				b.runHealthCheck(generation, passed+1)
+			})
+		default:
+			b.closeBreaker(nil)
+		}
+	})
+}
+
+func (b *Breaker) callHealthCheck() (err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			err = &PanicError{Value: val, Stack: debug.Stack()}
+		}
+	}()
+
+	return b.healthCheck()
+}
@@ -0,0 +1,155 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestBreakerHealthCheck(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	healthy := false
+	checks := 0
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(func() error {
+		checks++
+		if !healthy {
+			return errSomeError
+		}
+		return nil
+	}, 3)
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	// the breaker never half-opens, it checks the dependency itself
+	c.Advance(10 * time.Millisecond)
+	if checks != 1 {
+		t.Error("incorrect number of checks", checks)
+	}
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	// and tries again after the next timeout
+	c.Advance(5 * time.Millisecond)
+	if checks != 1 {
+		t.Error("incorrect number of checks", checks)
+	}
+	healthy = true
+	c.Advance(5 * time.Millisecond)
+	if checks != 2 {
+		t.Error("incorrect number of checks", checks)
+	}
+
+	// the remaining checks are spaced out by the timeout
+	c.Advance(9 * time.Millisecond)
+	if checks != 2 || breaker.GetState() != Open {
+		t.Error("incorrect number of checks", checks)
+	}
+	c.Advance(1 * time.Millisecond)
+	if checks != 3 || breaker.GetState() != Open {
+		t.Error("incorrect number of checks", checks)
+	}
+	c.Advance(10 * time.Millisecond)
+	if checks != 4 {
+		t.Error("incorrect number of checks", checks)
+	}
+	if breaker.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+	if err := breaker.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBreakerHealthCheckPanics(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(func() error {
+		panic("foo")
+	}, 1)
+
+	events := make(chan StateChange, 10)
+	breaker.OnStateChange(func(ev StateChange) {
+		events <- ev
+	})
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	<-events
+
+	c.Advance(10 * time.Millisecond)
+	if breaker.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	if m := breaker.Metrics(); !m.StateSince.Equal(time.Unix(0, 0)) {
+		t.Error("incorrect state time", m.StateSince)
+	}
+}
+
+func TestBreakerHealthCheckClose(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(func() error {
+		t.Error("health check should not run")
+		return nil
+	}, 1)
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	// closing cancels the pending check, and falls back to half-opening
+	breaker.Close()
+	c.Advance(time.Hour)
+	if breaker.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+
+	// forcing the state cancels it too
+	breaker = New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(func() error {
+		t.Error("health check should not run")
+		return errors.New("unreachable")
+	}, 1)
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	breaker.ForceOpen()
+	c.Advance(time.Hour)
+	if breaker.GetState() != ForcedOpen {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerHealthCheckRelapse(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	results := []error{nil, errSomeError, nil, nil}
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(func() error {
+		err := results[0]
+		results = results[1:]
+		return err
+	}, 2)
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	// a failure part way through starts the checks again from scratch
+	c.Advance(20 * time.Millisecond)
+	if len(results) != 2 || breaker.GetState() != Open {
+		t.Error("incorrect checks", len(results))
+	}
+	c.Advance(10 * time.Millisecond)
+	if len(results) != 1 || breaker.GetState() != Open {
+		t.Error("incorrect checks", len(results))
+	}
+	c.Advance(10 * time.Millisecond)
+	if len(results) != 0 || breaker.GetState() != Closed {
+		t.Error("incorrect checks", len(results))
+	}
+}
@@ -0,0 +1,106 @@
+// Package httpbreaker adapts the circuit-breaker resiliency pattern to HTTP clients, by providing
+// an http.RoundTripper which runs each request through a breaker.Breaker.
+package httpbreaker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/eapache/go-resiliency/breaker"
+)
+
+// StatusError is the error reported to the breaker (and therefore to any classifier it was
+// configured with) for a response which the failure rule considers a failure, even though the
+// request itself succeeded. It is never returned from RoundTrip; the response is returned instead.
+type StatusError struct {
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http response status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// DefaultFailureRule is the rule used by a Transport unless configured otherwise. It treats
+// transport errors and 5xx responses as failures.
+func DefaultFailureRule(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
+
+// Transport is an http.RoundTripper which runs each request through a circuit-breaker. When the
+// breaker is open, requests fail immediately with breaker.ErrBreakerOpen without being sent.
+type Transport struct {
+	base      http.RoundTripper
+	breaker   *breaker.Breaker
+	registry  *breaker.Registry
+	isFailure func(*http.Response, error) bool
+}
+
+// New constructs a Transport which sends requests using the given base transport (or
+// http.DefaultTransport if nil), running every request through the given breaker.
+func New(base http.RoundTripper, b *breaker.Breaker) *Transport {
+	return newTransport(base, b, nil)
+}
+
+// NewPerHost constructs a Transport which sends requests using the given base transport (or
+// http.DefaultTransport if nil), running each request through the breaker for its host (as in
+// the URL, including any port) from the given registry.
+func NewPerHost(base http.RoundTripper, r *breaker.Registry) *Transport {
+	return newTransport(base, nil, r)
+}
+
+func newTransport(base http.RoundTripper, b *breaker.Breaker, r *breaker.Registry) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:      base,
+		breaker:   b,
+		registry:  r,
+		isFailure: DefaultFailureRule,
+	}
+}
+
+// WithFailureRule configures the rule used to decide whether the outcome of a request counts as a
+// failure for the breaker. Outcomes which are not failures count as successes, even if the request
+// returned an error. The response and error are always passed along to the caller unchanged.
+func (t *Transport) WithFailureRule(isFailure func(*http.Response, error) bool) *Transport {
+	t.isFailure = isFailure
+	return t
+}
+
+// RoundTrip implements the http.RoundTripper interface. The request's context is passed through to
+// the breaker as in Breaker.RunCtx, so requests cancelled by the caller are not counted as failures.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breaker
+	if t.registry != nil {
+		b = t.registry.Get(req.URL.Host)
+	}
+
+	var resp *http.Response
+	var rtErr error
+	ran := false
+
+	err := b.RunCtx(req.Context(), func(ctx context.Context) error {
+		ran = true
+		resp, rtErr = t.base.RoundTrip(req)
+		if !t.isFailure(resp, rtErr) {
+			return nil
+		}
+		if rtErr != nil {
+			return rtErr
+		}
+		return &StatusError{StatusCode: resp.StatusCode}
+	})
+
+	if !ran {
+		// RoundTrippers must always close the request body, even on errors
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	return resp, rtErr
+}
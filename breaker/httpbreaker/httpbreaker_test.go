@@ -0,0 +1,133 @@
+package httpbreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+)
+
+func newServer(status *int32, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+	}))
+}
+
+func get(client *http.Client, url string) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestTransport(t *testing.T) {
+	status, hits := int32(http.StatusOK), int32(0)
+	server := newServer(&status, &hits)
+	defer server.Close()
+
+	b := breaker.New(2, 1, time.Hour)
+	client := &http.Client{Transport: New(nil, b)}
+
+	// 4xx responses are not failures by default
+	atomic.StoreInt32(&status, http.StatusNotFound)
+	for i := 0; i < 3; i++ {
+		if code, err := get(client, server.URL); code != http.StatusNotFound || err != nil {
+			t.Error(code, err)
+		}
+	}
+	if b.GetState() != breaker.Closed {
+		t.Error("incorrect state")
+	}
+
+	// 5xx responses are returned, but count as failures
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		if code, err := get(client, server.URL); code != http.StatusServiceUnavailable || err != nil {
+			t.Error(code, err)
+		}
+	}
+	if b.GetState() != breaker.Open {
+		t.Error("incorrect state")
+	}
+
+	// once open, requests are rejected without reaching the server
+	atomic.StoreInt32(&hits, 0)
+	if _, err := get(client, server.URL); !errors.Is(err, breaker.ErrBreakerOpen) {
+		t.Error(err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("request reached the server")
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	status, hits := int32(http.StatusOK), int32(0)
+	server := newServer(&status, &hits)
+	server.Close()
+
+	b := breaker.New(1, 1, time.Hour)
+	client := &http.Client{Transport: New(nil, b)}
+
+	if _, err := get(client, server.URL); err == nil || errors.Is(err, breaker.ErrBreakerOpen) {
+		t.Error(err)
+	}
+	if b.GetState() != breaker.Open {
+		t.Error("incorrect state")
+	}
+}
+
+func TestTransportFailureRule(t *testing.T) {
+	status, hits := int32(http.StatusTooManyRequests), int32(0)
+	server := newServer(&status, &hits)
+	defer server.Close()
+
+	b := breaker.New(1, 1, time.Hour)
+	client := &http.Client{Transport: New(nil, b).WithFailureRule(func(resp *http.Response, err error) bool {
+		return err != nil || resp.StatusCode == http.StatusTooManyRequests
+	})}
+
+	if code, err := get(client, server.URL); code != http.StatusTooManyRequests || err != nil {
+		t.Error(code, err)
+	}
+	if b.GetState() != breaker.Open {
+		t.Error("incorrect state")
+	}
+}
+
+func TestTransportPerHost(t *testing.T) {
+	badStatus, badHits := int32(http.StatusInternalServerError), int32(0)
+	bad := newServer(&badStatus, &badHits)
+	defer bad.Close()
+	goodStatus, goodHits := int32(http.StatusOK), int32(0)
+	good := newServer(&goodStatus, &goodHits)
+	defer good.Close()
+
+	r := breaker.NewRegistry(func(key string) *breaker.Breaker {
+		return breaker.New(1, 1, time.Hour)
+	})
+	client := &http.Client{Transport: NewPerHost(nil, r)}
+
+	if code, err := get(client, bad.URL); code != http.StatusInternalServerError || err != nil {
+		t.Error(code, err)
+	}
+	if _, err := get(client, bad.URL); !errors.Is(err, breaker.ErrBreakerOpen) {
+		t.Error(err)
+	}
+
+	// other hosts are unaffected
+	if code, err := get(client, good.URL); code != http.StatusOK || err != nil {
+		t.Error(code, err)
+	}
+
+	states := r.States()
+	if len(states) != 2 || states[bad.Listener.Addr().String()] != breaker.Open {
+		t.Error("incorrect states", states)
+	}
+}
@@ -0,0 +1,81 @@
+package breaker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a point-in-time snapshot of the activity of a Breaker, as returned by Breaker.Metrics.
+type Metrics struct {
+	// State is the state of the breaker, as returned by GetState.
+	State State
+	// StateSince is the time at which the breaker entered its current state, or was constructed.
+	StateSince time.Time
+
+	// Successes, Failures and Ignored are the number of calls the breaker has run, broken down by
+	// how their results were classified (see WithClassifier). Panics count as failures. Calls made
+	// while the breaker is forced closed are included, even though they do not affect its state.
+	Successes, Failures, Ignored uint64
+	// SlowCalls is the number of calls which exceeded the slow-call threshold, if any, regardless of
+	// how their results were classified.
+	SlowCalls uint64
+	// Rejections is the number of calls which were not run because the breaker was open.
+	Rejections uint64
+
+	// WindowTotal, WindowFailures and WindowSlowCalls are the counts within the breaker's current
+	// window if it was configured with a failure rate, and zero otherwise.
+	WindowTotal, WindowFailures, WindowSlowCalls int
+}
+
+type counters struct {
+	successes, failures, ignored, slowCalls, rejections uint64
+}
+
+func (c *counters) record(o outcome) {
+	switch o {
+	case success:
+		atomic.AddUint64(&c.successes, 1)
+	case failure:
+		atomic.AddUint64(&c.failures, 1)
+	case ignored:
+		atomic.AddUint64(&c.ignored, 1)
+	}
+}
+
+// Metrics returns a snapshot of the breaker's activity. The cumulative counters are maintained
+// atomically and the breaker's main lock is not taken, so it is cheap enough to call frequently
+// (e.g. from a metrics scraper). Since the values are read independently, they are not guaranteed
+// to be mutually consistent if calls are in progress. In particular, the state is read without
+// performing the lazy transition from open to half-open (see GetState): a breaker whose open timeout
+// has passed is reported as half-open, but StateSince remains the time at which it opened until it is
+// next used. With a Store, the state is the one this breaker last saw rather than the one in the
+// store. It is safe to call Metrics concurrently with any other method on the same Breaker.
+func (b *Breaker) Metrics() Metrics {
+	m := Metrics{
+		State:      b.peekState(),
+		StateSince: time.Unix(0, atomic.LoadInt64(&b.stateSince)),
+		Successes:  atomic.LoadUint64(&b.counters.successes),
+		Failures:   atomic.LoadUint64(&b.counters.failures),
+		Ignored:    atomic.LoadUint64(&b.counters.ignored),
+		SlowCalls:  atomic.LoadUint64(&b.counters.slowCalls),
+		Rejections: atomic.LoadUint64(&b.counters.rejections),
+	}
+
+	if b.window != nil {
+		b.windowLock.Lock()
+		m.WindowTotal, m.WindowFailures, m.WindowSlowCalls = b.window.counts(b.clock.Now())
+		b.windowLock.Unlock()
+	}
+
+	return m
+}
+
+// peekState returns the state of the breaker as GetState would, but without taking the lock to
+// perform the transition from open to half-open.
+func (b *Breaker) peekState() State {
+	state := (State)(atomic.LoadUint32((*uint32)(&b.state)))
+	if state == Open && !b.clock.Now().Before(time.Unix(0, atomic.LoadInt64(&b.openUntil))) {
+		return HalfOpen
+	}
+	return state
+}
@@ -0,0 +1,77 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+func TestBreakerMetrics(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	errIgnored := errors.New("errIgnored")
+	breaker := New(0, 1, 10*time.Millisecond).
+		WithClock(c).
+		WithCountedFailureRate(0.5, 4, 10).
+		WithSlowCallThreshold(time.Second, 1).
+		WithClassifier(retrier.BlacklistClassifier{errIgnored})
+
+	m := breaker.Metrics()
+	if m.State != Closed || !m.StateSince.Equal(time.Unix(1000, 0)) {
+		t.Error("incorrect metrics", m)
+	}
+
+	c.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := breaker.Run(func() error { return errIgnored }); err != errIgnored {
+		t.Error(err)
+	}
+	if err := breaker.Run(func() error {
+		c.Advance(2 * time.Second)
+		return errSomeError
+	}); err != errSomeError {
+		t.Error(err)
+	}
+
+	m = breaker.Metrics()
+	if m.Successes != 2 || m.Failures != 1 || m.Ignored != 1 || m.SlowCalls != 1 || m.Rejections != 0 {
+		t.Error("incorrect metrics", m)
+	}
+	if m.WindowTotal != 3 || m.WindowFailures != 1 || m.WindowSlowCalls != 1 {
+		t.Error("incorrect metrics", m)
+	}
+
+	// trip the breaker and get rejected
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := breaker.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	m = breaker.Metrics()
+	if m.State != Open || !m.StateSince.Equal(time.Unix(1062, 0)) {
+		t.Error("incorrect metrics", m)
+	}
+	if m.Successes != 2 || m.Failures != 2 || m.Rejections != 1 {
+		t.Error("incorrect metrics", m)
+	}
+	if m.WindowTotal != 0 || m.WindowFailures != 0 || m.WindowSlowCalls != 0 {
+		t.Error("incorrect metrics", m)
+	}
+
+	// an expired timeout is reported without taking the lock to transition
+	c.Advance(10 * time.Millisecond)
+	breaker.lock.Lock()
+	m = breaker.Metrics()
+	breaker.lock.Unlock()
+	if m.State != HalfOpen || !m.StateSince.Equal(time.Unix(1062, 0)) {
+		t.Error("incorrect metrics", m)
+	}
+}
@@ -0,0 +1,15 @@
+package breaker
+
+// WithParent makes the breaker a child of the given parent breaker, for example one breaker per
+// endpoint under a breaker for the whole host. A call through the child is only run if both the
+// child and the parent admit it, and is rejected with ErrBreakerOpen otherwise; once run, its outcome
+// is counted by both, each according to its own configuration (classifier, thresholds, slow-call
+// threshold and so on), except that a panic is handled according to the child's panic policy (see
+// WithPanicRecovery) alone. So failures across all the children of a parent can open it, cutting them
+// all off at once. The child's state (as returned by GetState) does not reflect the parent's; calls
+// rejected by the parent count as rejections in both. A parent may itself have a parent, and may be
+// used directly as well as through its children. It must be called before the breaker is used.
+func (b *Breaker) WithParent(parent *Breaker) *Breaker {
+	b.parent = parent
+	return b
+}
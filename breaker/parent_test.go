@@ -0,0 +1,116 @@
+package breaker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestBreakerParent(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	host := New(3, 1, 10*time.Millisecond).WithClock(c)
+	a := New(2, 1, 10*time.Millisecond).WithClock(c).WithParent(host)
+	b := New(2, 1, 10*time.Millisecond).WithClock(c).WithParent(host)
+
+	// failures of each child count towards the parent
+	if err := a.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := b.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := a.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if host.GetState() != Open || a.GetState() != Open || b.GetState() != Closed {
+		t.Error("incorrect states")
+	}
+
+	// and the open parent rejects calls through every child
+	if err := b.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	if b.Metrics().Rejections != 1 || host.Metrics().Rejections != 1 {
+		t.Error("incorrect rejections")
+	}
+
+	// successes through a child close the parent again
+	c.Advance(10 * time.Millisecond)
+	if err := b.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if host.GetState() != Closed || a.GetState() != HalfOpen {
+		t.Error("incorrect states")
+	}
+
+	// a child rejecting a call never asks the parent
+	a.ForceOpen()
+	if err := a.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	if host.Metrics().Rejections != 1 {
+		t.Error("incorrect rejections")
+	}
+}
+
+func TestBreakerParentHalfOpenLimit(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	host := New(1, 1, 10*time.Millisecond).WithClock(c)
+	child := New(1, 1, 10*time.Millisecond).WithClock(c).WithHalfOpenLimit(1).WithParent(host)
+
+	if err := child.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(10 * time.Millisecond)
+	host.ForceOpen()
+
+	// the child's half-open slot is released when the parent rejects the call
+	if err := child.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	host.Reset()
+	if err := child.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if child.GetState() != Closed {
+		t.Error("incorrect state")
+	}
+}
+
+func panicsInParentTest() error {
+	panic("foo")
+}
+
+func TestBreakerParentPanics(t *testing.T) {
+	// the child's policy applies, keeping the stack of the original panic
+	host := New(1, 1, time.Hour)
+	child := New(1, 1, time.Hour).WithPanicRecovery().WithParent(host)
+
+	err := child.Run(panicsInParentTest)
+	panicErr, ok := err.(*PanicError)
+	if !ok || panicErr.Value != "foo" {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(panicErr.Stack), "panicsInParentTest") {
+		t.Error("stack does not contain the panicking function")
+	}
+	if host.GetState() != Open || child.GetState() != Open {
+		t.Error("panic not counted")
+	}
+
+	// even if the parent would recover
+	host = New(1, 1, time.Hour).WithPanicRecovery()
+	child = New(1, 1, time.Hour).WithParent(host)
+
+	defer func() {
+		if recover() != "foo" {
+			t.Error("child did not re-panic")
+		}
+		if host.GetState() != Open || child.GetState() != Open {
+			t.Error("panic not counted")
+		}
+	}()
+	_ = child.Run(panicsInParentTest)
+}
@@ -0,0 +1,155 @@
+package breaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+// Registry manages a set of breakers identified by key, for example one per downstream host.
+// Breakers are created lazily the first time their key is requested.
+type Registry struct {
+	newBreaker func(key string) *Breaker
+	clock      clock.Clock
+
+	lock    sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	lastUsed int64 // accessed atomically
+	breaker  *Breaker
+}
+
+// NewRegistry constructs a new, empty registry. The given function is called to construct the
+// breaker for a key the first time that key is requested; it acts as the template for every
+// breaker in the registry, and may use the key to customize individual breakers if desired. It is
+// called without holding any lock on the registry, so a slow constructor only delays the caller
+// asking for that key, and the constructor may itself call Get (for example to find a parent breaker
+// for WithParent) as long as it does not request the same key. If several goroutines request a new
+// key at once the function may be called more than once for it; only one of the breakers is kept,
+// and the others are closed (see Breaker.Close) and discarded.
+func NewRegistry(newBreaker func(key string) *Breaker) *Registry {
+	return &Registry{
+		newBreaker: newBreaker,
+		clock:      clock.New(),
+		entries:    make(map[string]*registryEntry),
+	}
+}
+
+// WithClock configures the registry to use the given clock instead of the real one when tracking
+// how long breakers have been idle, for example a clock.Fake in tests. It does not affect the
+// breakers themselves. It must be called before the registry is used.
+func (r *Registry) WithClock(c clock.Clock) *Registry {
+	r.clock = c
+	return r
+}
+
+// Get returns the breaker for the given key, constructing it if necessary, and marks it as used.
+// It is safe to call Get concurrently on the same Registry.
+func (r *Registry) Get(key string) *Breaker {
+	now := r.clock.Now().UnixNano()
+
+	r.lock.RLock()
+	entry, ok := r.entries[key]
+	r.lock.RUnlock()
+
+	if !ok {
+		b := r.newBreaker(key)
+
+		r.lock.Lock()
+		entry, ok = r.entries[key]
+		if !ok {
+			// set lastUsed before anyone else can see the entry, so EvictIdle can't drop it
+			entry = &registryEntry{lastUsed: now, breaker: b}
+			r.entries[key] = entry
+		}
+		r.lock.Unlock()
+
+		if ok {
+			// someone else got there first
+			b.Close()
+		}
+	}
+
+	atomic.StoreInt64(&entry.lastUsed, now)
+	return entry.breaker
+}
+
+// Remove removes the breaker for the given key from the registry, if present, and closes it (see
+// Breaker.Close). Anyone still holding the removed breaker may keep using it, but subsequent calls to
+// Get will construct a new one.
+func (r *Registry) Remove(key string) {
+	r.lock.Lock()
+	entry, ok := r.entries[key]
+	delete(r.entries, key)
+	r.lock.Unlock()
+
+	if ok {
+		entry.breaker.Close()
+	}
+}
+
+// Len returns the number of breakers in the registry.
+func (r *Registry) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return len(r.entries)
+}
+
+// Range calls fn for each breaker in the registry, in no particular order, stopping early if fn
+// returns false. It operates on a snapshot of the registry, so fn may safely call other methods on
+// the same Registry.
+func (r *Registry) Range(fn func(key string, b *Breaker) bool) {
+	r.lock.RLock()
+	keys := make([]string, 0, len(r.entries))
+	breakers := make([]*Breaker, 0, len(r.entries))
+	for key, entry := range r.entries {
+		keys = append(keys, key)
+		breakers = append(breakers, entry.breaker)
+	}
+	r.lock.RUnlock()
+
+	for i := range keys {
+		if !fn(keys[i], breakers[i]) {
+			return
+		}
+	}
+}
+
+// States returns the current State of every breaker in the registry, by key.
+func (r *Registry) States() map[string]State {
+	states := make(map[string]State)
+	r.Range(func(key string, b *Breaker) bool {
+		states[key] = b.GetState()
+		return true
+	})
+	return states
+}
+
+// EvictIdle removes every breaker which has not been returned by Get for at least maxIdle, and
+// returns the number of breakers removed. Call it periodically to bound the memory used by a
+// registry with an unbounded set of keys (such as one breaker per tenant). As with Remove, evicted
+// breakers are closed, and one which is evicted while still in use keeps working but is no longer
+// tracked.
+func (r *Registry) EvictIdle(maxIdle time.Duration) int {
+	cutoff := r.clock.Now().Add(-maxIdle).UnixNano()
+
+	r.lock.Lock()
+	var evicted []*Breaker
+	for key, entry := range r.entries {
+		if atomic.LoadInt64(&entry.lastUsed) <= cutoff {
+			delete(r.entries, key)
+			evicted = append(evicted, entry.breaker)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, b := range evicted {
+		b.Close()
+	}
+	return len(evicted)
+}
@@ -0,0 +1,147 @@
+package breaker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestRegistry(t *testing.T) {
+	created := 0
+	r := NewRegistry(func(key string) *Breaker {
+		created++
+		return New(1, 1, time.Hour)
+	})
+
+	a := r.Get("a")
+	if r.Get("a") != a {
+		t.Error("breaker not re-used")
+	}
+	b := r.Get("b")
+	if a == b || created != 2 || r.Len() != 2 {
+		t.Error("incorrect breakers")
+	}
+
+	if err := a.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	states := r.States()
+	if len(states) != 2 || states["a"] != Open || states["b"] != Closed {
+		t.Error("incorrect states", states)
+	}
+
+	seen := 0
+	r.Range(func(key string, b *Breaker) bool {
+		seen++
+		return false
+	})
+	if seen != 1 {
+		t.Error("range did not stop early")
+	}
+
+	r.Remove("a")
+	if r.Len() != 1 || r.Get("a") == a || created != 3 {
+		t.Error("breaker not removed")
+	}
+}
+
+func TestRegistryEvictIdle(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	r := NewRegistry(func(key string) *Breaker {
+		return New(1, 1, time.Hour)
+	}).WithClock(c)
+
+	a := r.Get("a")
+	r.Get("b")
+
+	c.Advance(time.Minute)
+	r.Get("b")
+	c.Advance(30 * time.Second)
+
+	if evicted := r.EvictIdle(time.Minute); evicted != 1 {
+		t.Error("incorrect evictions", evicted)
+	}
+	if r.Len() != 1 || r.Get("a") == a {
+		t.Error("incorrect breaker evicted")
+	}
+
+	c.Advance(time.Hour)
+	if evicted := r.EvictIdle(time.Minute); evicted != 2 {
+		t.Error("incorrect evictions", evicted)
+	}
+	if r.Len() != 0 {
+		t.Error("breakers not evicted")
+	}
+}
+
+func TestRegistryClosesRemoved(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	checks := 0
+	r := NewRegistry(func(key string) *Breaker {
+		return New(1, 1, time.Second).WithClock(c).WithHealthCheck(func() error {
+			checks++
+			return nil
+		}, 1)
+	}).WithClock(c)
+
+	for _, key := range []string{"a", "b"} {
+		if err := r.Get(key).Run(returnsError); err != errSomeError {
+			t.Error(err)
+		}
+	}
+
+	r.Remove("a")
+	if evicted := r.EvictIdle(0); evicted != 1 {
+		t.Error("incorrect evictions", evicted)
+	}
+
+	c.Advance(time.Minute)
+	if checks != 0 {
+		t.Error("health check ran after removal", checks)
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	r := NewRegistry(func(key string) *Breaker {
+		return New(1, 1, time.Hour)
+	})
+
+	breakers := make([]*Breaker, 10)
+	wg := &sync.WaitGroup{}
+	for i := range breakers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			breakers[i] = r.Get("key")
+			r.EvictIdle(time.Hour)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range breakers {
+		if breakers[i] != breakers[0] {
+			t.Error("multiple breakers created for one key")
+		}
+	}
+}
+
+func TestRegistryParents(t *testing.T) {
+	var r *Registry
+	r = NewRegistry(func(key string) *Breaker {
+		b := New(1, 1, time.Hour)
+		if key != "host" {
+			// constructing a breaker may use the registry itself
+			b.WithParent(r.Get("host"))
+		}
+		return b
+	})
+
+	if err := r.Get("endpoint").Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if r.Len() != 2 || r.Get("host").GetState() != Open {
+		t.Error("incorrect parent")
+	}
+}
@@ -0,0 +1,119 @@
+package breaker
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is the state of a breaker at a point in time. It is used to share state between instances
+// through a Store, and can be saved (for example as JSON) and restored with WithSnapshot so that the
+// breaker survives a restart.
+type Snapshot struct {
+	// State is the state of the breaker.
+	State State `json:"state"`
+	// Errors and Successes are the consecutive error and success counts used when the breaker is not
+	// tracking a failure rate (see New).
+	Errors    int `json:"errors"`
+	Successes int `json:"successes"`
+	// LastError is the time of the most recent error counted towards Errors.
+	LastError time.Time `json:"lastError"`
+	// OpenUntil is the time at which the breaker half-opens, if it is open, and the zero time if it
+	// has never opened.
+	OpenUntil time.Time `json:"openUntil"`
+	// Opens is the number of times the breaker has opened since it was last closed (see
+	// WithOpenBackoff).
+	Opens int `json:"opens"`
+	// Since is the time at which the breaker entered its current state.
+	Since time.Time `json:"since"`
+}
+
+// Snapshot returns the current state of the breaker, for example to be saved on shutdown and
+// restored with WithSnapshot. Failure-rate windows, half-open call limits and metrics are not
+// included.
+func (b *Breaker) Snapshot() Snapshot {
+	b.lock.Lock()
+	defer b.unlock()
+
+	b.refresh()
+	return b.snapshot()
+}
+
+// WithSnapshot restores the breaker to the state in the given snapshot, as returned by Snapshot. A
+// breaker restored open stays open until the time it would have half-opened (or been health
+// checked), even across a restart. If the breaker has a Store, the state in the store takes
+// precedence once the breaker is used. It must be called after WithClock and WithHealthCheck, if
+// they are used, and before the breaker is used.
+func (b *Breaker) WithSnapshot(s Snapshot) *Breaker {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.restore(s)
+	return b
+}
+
+func (b *Breaker) restore(s Snapshot) {
+	if s.State != b.state {
+		// another instance changed the state; this also drops anything tied
+		// to the old one, like half-open slots and pending health checks
+		b.changeState(s.State, nil)
+	}
+
+	b.errors = s.Errors
+	b.successes = s.Successes
+	b.lastError = s.LastError
+	b.opens = s.Opens
+
+	openUntil := toUnixNano(s.OpenUntil)
+	switch {
+	case b.state != Open || b.healthCheck == nil || b.closed:
+		atomic.StoreInt64(&b.openUntil, openUntil)
+	case b.healthTimer == nil:
+		// someone else opened the breaker, so check on it ourselves rather
+		// than relying on them to still be around to do it
+		b.awaitHealthCheck(openUntil)
+	default:
+		// our own check is already pending
+		b.healthDue = openUntil
+	}
+	if !s.Since.IsZero() {
+		atomic.StoreInt64(&b.stateSince, s.Since.UnixNano())
+	}
+}
+
+func (b *Breaker) snapshot() Snapshot {
+	openUntil := atomic.LoadInt64(&b.openUntil)
+	if openUntil == math.MaxInt64 {
+		// holding the breaker open for a health check is our own business;
+		// everyone else needs to know when the check is due
+		openUntil = b.healthDue
+	}
+
+	return Snapshot{
+		State:     b.state,
+		Errors:    b.errors,
+		Successes: b.successes,
+		LastError: b.lastError,
+		OpenUntil: fromUnixNano(openUntil),
+		Opens:     b.opens,
+		Since:     time.Unix(0, atomic.LoadInt64(&b.stateSince)),
+	}
+}
+
+func toUnixNano(t time.Time) int64 {
+	switch {
+	case t.IsZero():
+		return 0
+	case t.After(time.Unix(0, math.MaxInt64)):
+		return math.MaxInt64
+	default:
+		return t.UnixNano()
+	}
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
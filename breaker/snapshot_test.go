@@ -0,0 +1,90 @@
+package breaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+func TestBreakerSnapshot(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	breaker := New(2, 1, 10*time.Millisecond).WithClock(c)
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	s := breaker.Snapshot()
+	if s.State != Closed || s.Errors != 1 || !s.OpenUntil.IsZero() {
+		t.Error("incorrect snapshot", s)
+	}
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(4 * time.Millisecond)
+
+	data, err := json.Marshal(breaker.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = Snapshot{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.State != Open || !s.Since.Equal(time.Unix(0, 0)) || !s.OpenUntil.Equal(time.Unix(0, 0).Add(10*time.Millisecond)) {
+		t.Error("incorrect snapshot", s)
+	}
+
+	// the restored breaker half-opens when the original would have
+	restored := New(2, 1, 10*time.Millisecond).WithClock(c).WithSnapshot(s)
+	if err := restored.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+	if !restored.Metrics().StateSince.Equal(time.Unix(0, 0)) {
+		t.Error("incorrect state since")
+	}
+	c.Advance(6 * time.Millisecond)
+	if restored.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+}
+
+func TestBreakerSnapshotHealthCheck(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	checks := 0
+	check := func() error {
+		checks++
+		return nil
+	}
+
+	breaker := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(check, 1)
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	s := breaker.Snapshot()
+	breaker.Close()
+
+	if !s.OpenUntil.Equal(time.Unix(0, 0).Add(10 * time.Millisecond)) {
+		t.Error("incorrect snapshot", s)
+	}
+
+	// the restored breaker checks when the original would have
+	c.Advance(5 * time.Millisecond)
+	restored := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(check, 1).WithSnapshot(s)
+	plain := New(1, 1, 10*time.Millisecond).WithClock(c).WithSnapshot(s)
+	c.Advance(4 * time.Millisecond)
+	if checks != 0 || restored.GetState() != Open || plain.GetState() != Open {
+		t.Error("checked too early", checks)
+	}
+	c.Advance(1 * time.Millisecond)
+	if checks != 1 || restored.GetState() != Closed {
+		t.Error("health check did not run", checks)
+	}
+
+	// and without a health check it half-opens instead
+	if plain.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+}
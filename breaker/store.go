@@ -0,0 +1,118 @@
+package breaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the state of one or more breakers, so that instances of the same logical breaker (for
+// example in different replicas of a service) trip and recover together. Implementations must be
+// safe for concurrent use.
+type Store interface {
+	// Load returns the current snapshot. A store which has never been updated returns the zero
+	// Snapshot, which is a closed breaker.
+	Load() (Snapshot, error)
+	// Update atomically reads the current snapshot, passes it to fn to be modified in place, and
+	// saves the result. It must call fn at most once, and must not call it at all if it returns an
+	// error.
+	Update(fn func(*Snapshot)) error
+}
+
+// MemoryStore is a Store which holds the snapshot in memory. It can be shared by breakers in the same
+// process.
+type MemoryStore struct {
+	lock     sync.Mutex
+	snapshot Snapshot
+}
+
+// NewMemoryStore constructs a new MemoryStore holding a closed breaker.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load() (Snapshot, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.snapshot, nil
+}
+
+// Update implements Store.
+func (m *MemoryStore) Update(fn func(*Snapshot)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	fn(&m.snapshot)
+	return nil
+}
+
+// WithStore configures the breaker to share its state and error/success counts with every other
+// breaker using the same store. The breaker Loads the latest state from the store every time it is
+// used, so a breaker opened by one instance rejects calls in all of them, and each change is made as
+// a single Update of the store; calls which do not change anything (such as successes while closed)
+// do not write to it. Failure-rate windows, half-open call limits and metrics remain local to each
+// instance. Every instance with a health check runs it for itself once the breaker is due to
+// recover, while instances without one half-open at that time as usual, so the breaker recovers
+// even if the instance which opened it has gone away. If the store returns an error the change is
+// applied locally only, and the error is passed to "onError" if it is not nil. The callback is called
+// after the breaker's lock has been released, so it may safely use the breaker. It must be called
+// before the breaker is used.
+func (b *Breaker) WithStore(store Store, onError func(error)) *Breaker {
+	b.store = store
+	b.onStoreError = onError
+	return b
+}
+
+// update runs fn, which changes the state of the breaker, against the latest state from the store
+// and then saves the result back to the store. It must be called with the lock held.
+func (b *Breaker) update(fn func()) {
+	if b.store == nil {
+		fn()
+		return
+	}
+
+	ran := false
+	err := b.store.Update(func(s *Snapshot) {
+		b.restore(*s)
+		fn()
+		ran = true
+		*s = b.snapshot()
+	})
+	if err != nil {
+		if !ran {
+			fn()
+		}
+		b.storeErr = err
+	}
+}
+
+// refresh brings the breaker up to date with the store, if any, and then moves it from open to
+// half-open if the timeout has passed. The store is only read, unless the breaker does change state.
+// It must be called with the lock held.
+func (b *Breaker) refresh() {
+	if b.store != nil {
+		if s, err := b.store.Load(); err != nil {
+			b.storeErr = err
+		} else {
+			b.restore(s)
+		}
+	}
+
+	if b.state == Open && !b.clock.Now().Before(time.Unix(0, atomic.LoadInt64(&b.openUntil))) {
+		b.update(b.expireOpen)
+	}
+}
+
+// unlock releases the lock and then reports the last error from the store, if any, so that the
+// callback is free to use the breaker.
+func (b *Breaker) unlock() {
+	err := b.storeErr
+	b.storeErr = nil
+	b.lock.Unlock()
+
+	if err != nil && b.onStoreError != nil {
+		b.onStoreError(err)
+	}
+}
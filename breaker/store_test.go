@@ -0,0 +1,208 @@
+package breaker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/clock"
+)
+
+type failingStore struct{}
+
+func (failingStore) Load() (Snapshot, error)      { return Snapshot{}, errSomeError }
+func (failingStore) Update(func(*Snapshot)) error { return errSomeError }
+
+// countingStore counts the loads and updates of the store it wraps.
+type countingStore struct {
+	Store
+	loads, updates int
+}
+
+func (c *countingStore) Load() (Snapshot, error) {
+	c.loads++
+	return c.Store.Load()
+}
+
+func (c *countingStore) Update(fn func(*Snapshot)) error {
+	c.updates++
+	return c.Store.Update(fn)
+}
+
+func TestBreakerSharedStore(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	store := NewMemoryStore()
+	a := New(2, 1, 10*time.Millisecond).WithClock(c).WithStore(store, nil)
+	b := New(2, 1, 10*time.Millisecond).WithClock(c).WithStore(store, nil)
+
+	// errors are counted across both breakers
+	if err := a.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := b.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if a.GetState() != Open || b.GetState() != Open {
+		t.Error("breakers did not open together")
+	}
+	if err := a.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+
+	// and they recover together
+	c.Advance(10 * time.Millisecond)
+	if err := a.Run(returnsSuccess); err != nil {
+		t.Error(err)
+	}
+	if b.GetState() != Closed {
+		t.Error("breaker did not see the other close")
+	}
+
+	s, err := store.Load()
+	if err != nil {
+		t.Error(err)
+	}
+	if s.State != Closed || !s.Since.Equal(c.Now()) {
+		t.Error("incorrect snapshot", s)
+	}
+
+	b.ForceOpen()
+	if err := a.Run(returnsSuccess); err != ErrBreakerOpen {
+		t.Error(err)
+	}
+}
+
+func TestBreakerStoreError(t *testing.T) {
+	var storeErr error
+	var breaker *Breaker
+	breaker = New(1, 1, 10*time.Millisecond).WithStore(failingStore{}, func(err error) {
+		// the callback may use the breaker
+		breaker.Metrics()
+		storeErr = err
+	})
+
+	// the breaker still works locally
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if breaker.GetState() != Open {
+		t.Error("breaker did not open")
+	}
+	if storeErr != errSomeError {
+		t.Error(storeErr)
+	}
+}
+
+func TestStateText(t *testing.T) {
+	for s := Closed; s <= ForcedClosed; s++ {
+		data, err := json.Marshal(s)
+		if err != nil {
+			t.Error(err)
+		}
+		var decoded State
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != s {
+			t.Error(s, string(data), decoded, err)
+		}
+	}
+
+	if _, err := State(42).MarshalText(); err == nil {
+		t.Error("expected error")
+	}
+	var s State
+	if err := s.UnmarshalText([]byte("ajar")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBreakerStoreWrites(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	store := &countingStore{Store: NewMemoryStore()}
+	breaker := New(2, 1, 10*time.Millisecond).WithClock(c).WithStore(store, nil)
+
+	// calls which change nothing only read the store
+	for i := 0; i < 100; i++ {
+		if err := breaker.Run(returnsSuccess); err != nil {
+			t.Error(err)
+		}
+	}
+	if store.loads != 100 || store.updates != 0 {
+		t.Error("incorrect store usage", store.loads, store.updates)
+	}
+
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if err := breaker.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	if store.updates != 2 {
+		t.Error("incorrect store usage", store.updates)
+	}
+
+	// the lazy transition to half-open is written once
+	c.Advance(10 * time.Millisecond)
+	breaker.GetState()
+	breaker.GetState()
+	if store.updates != 3 {
+		t.Error("incorrect store usage", store.updates)
+	}
+}
+
+func TestBreakerStoreHealthCheck(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	store := NewMemoryStore()
+	checks := 0
+	check := func() error {
+		checks++
+		return nil
+	}
+
+	// the instance which opens the breaker dies before its health check can
+	// run, which we simulate by never advancing its clock
+	dead := New(1, 1, 10*time.Millisecond).WithClock(clock.NewFake(time.Unix(0, 0))).WithHealthCheck(check, 1).WithStore(store, nil)
+	plain := New(1, 1, 10*time.Millisecond).WithClock(c).WithStore(store, nil)
+	if err := dead.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	s, err := store.Load()
+	if err != nil || s.State != Open || !s.OpenUntil.Equal(time.Unix(0, 0).Add(10*time.Millisecond)) {
+		t.Error("incorrect snapshot", s, err)
+	}
+
+	// a replica without a health check half-opens when the check was due
+	if plain.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	c.Advance(10 * time.Millisecond)
+	if plain.GetState() != HalfOpen {
+		t.Error("incorrect state")
+	}
+	if err := plain.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+
+	// a replica with a health check checks for itself
+	checked := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(check, 1).WithStore(store, nil)
+	if checked.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	c.Advance(10 * time.Millisecond)
+	if checks != 1 || checked.GetState() != Closed || plain.GetState() != Closed {
+		t.Error("health check did not run", checks)
+	}
+
+	// even if it only joins long after the check was due
+	if err := dead.Run(returnsError); err != errSomeError {
+		t.Error(err)
+	}
+	c.Advance(2 * time.Hour)
+	late := New(1, 1, 10*time.Millisecond).WithClock(c).WithHealthCheck(check, 1).WithStore(store, nil)
+	if late.GetState() != Open {
+		t.Error("incorrect state")
+	}
+	c.Advance(0)
+	if checks != 2 || late.GetState() != Closed {
+		t.Error("health check did not run", checks)
+	}
+}
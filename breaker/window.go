@@ -0,0 +1,133 @@
+package breaker
+
+import "time"
+
+// window is implemented by the strategies a Breaker can use to track the
+// outcome of recent calls when it is configured with a failure rate.
+type window interface {
+	record(now time.Time, failure, slow bool)
+	counts(now time.Time) (total, failures, slow int)
+	reset()
+}
+
+type bucket struct {
+	epoch                 int64
+	total, failures, slow int
+}
+
+// timeWindow tracks call outcomes in a rolling window of time, divided into a
+// fixed number of buckets. Buckets which have fallen out of the window are
+// lazily reset the next time they are written to, and skipped when counting.
+type timeWindow struct {
+	width   time.Duration
+	buckets []bucket
+}
+
+func newTimeWindow(window time.Duration, buckets int) *timeWindow {
+	if buckets < 1 {
+		buckets = 1
+	}
+	width := window / time.Duration(buckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &timeWindow{
+		width:   width,
+		buckets: make([]bucket, buckets),
+	}
+}
+
+func (w *timeWindow) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(w.width)
+}
+
+func (w *timeWindow) record(now time.Time, failure, slow bool) {
+	epoch := w.epoch(now)
+	n := int64(len(w.buckets))
+	b := &w.buckets[(epoch%n+n)%n]
+	if b.epoch != epoch {
+		*b = bucket{epoch: epoch}
+	}
+	b.total++
+	if failure {
+		b.failures++
+	}
+	if slow {
+		b.slow++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) (total, failures, slow int) {
+	epoch := w.epoch(now)
+	oldest := epoch - int64(len(w.buckets))
+	for _, b := range w.buckets {
+		if b.epoch > oldest && b.epoch <= epoch {
+			total += b.total
+			failures += b.failures
+			slow += b.slow
+		}
+	}
+	return total, failures, slow
+}
+
+func (w *timeWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
+
+// countWindow tracks the outcomes of the last "size" calls in a ring buffer,
+// regardless of when they occurred.
+type countWindow struct {
+	outcomes    []callOutcome
+	next, total int
+	failures    int
+	slow        int
+}
+
+type callOutcome struct {
+	failure, slow bool
+}
+
+func newCountWindow(size int) *countWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &countWindow{
+		outcomes: make([]callOutcome, size),
+	}
+}
+
+func (w *countWindow) record(now time.Time, failure, slow bool) {
+	if w.total == len(w.outcomes) {
+		evicted := w.outcomes[w.next]
+		if evicted.failure {
+			w.failures--
+		}
+		if evicted.slow {
+			w.slow--
+		}
+	} else {
+		w.total++
+	}
+
+	w.outcomes[w.next] = callOutcome{failure: failure, slow: slow}
+	if failure {
+		w.failures++
+	}
+	if slow {
+		w.slow++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+func (w *countWindow) counts(now time.Time) (total, failures, slow int) {
+	return w.total, w.failures, w.slow
+}
+
+func (w *countWindow) reset() {
+	for i := range w.outcomes {
+		w.outcomes[i] = callOutcome{}
+	}
+	w.next, w.total, w.failures, w.slow = 0, 0, 0, 0
+}
@@ -0,0 +1,93 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	w := newTimeWindow(10*time.Second, 10)
+	now := time.Unix(1000, 0)
+
+	w.record(now, false, false)
+	w.record(now, true, false)
+	w.record(now.Add(5*time.Second), true, false)
+
+	if total, failures, _ := w.counts(now.Add(5 * time.Second)); total != 3 || failures != 2 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	// the first bucket has now expired
+	if total, failures, _ := w.counts(now.Add(10 * time.Second)); total != 1 || failures != 1 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	// writing to a recycled bucket discards its old contents
+	w.record(now.Add(10*time.Second), false, false)
+	if total, failures, _ := w.counts(now.Add(10 * time.Second)); total != 2 || failures != 1 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	if total, failures, _ := w.counts(now.Add(time.Minute)); total != 0 || failures != 0 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	w.record(now, true, true)
+	w.reset()
+	if total, failures, slow := w.counts(now); total != 0 || failures != 0 || slow != 0 {
+		t.Error("incorrect counts", total, failures, slow)
+	}
+}
+
+func TestCountWindow(t *testing.T) {
+	w := newCountWindow(3)
+	now := time.Unix(1000, 0)
+
+	w.record(now, true, false)
+	w.record(now, false, false)
+	if total, failures, _ := w.counts(now); total != 2 || failures != 1 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	// time has no effect on a count window
+	w.record(now.Add(time.Hour), true, false)
+	if total, failures, _ := w.counts(now.Add(time.Hour)); total != 3 || failures != 2 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	// the oldest outcome is evicted once the window is full
+	w.record(now, false, false)
+	if total, failures, _ := w.counts(now); total != 3 || failures != 1 {
+		t.Error("incorrect counts", total, failures)
+	}
+	w.record(now, false, false)
+	w.record(now, false, false)
+	if total, failures, _ := w.counts(now); total != 3 || failures != 0 {
+		t.Error("incorrect counts", total, failures)
+	}
+
+	w.record(now, true, true)
+	w.reset()
+	if total, failures, slow := w.counts(now); total != 0 || failures != 0 || slow != 0 {
+		t.Error("incorrect counts", total, failures, slow)
+	}
+}
+
+func TestWindowSlowCalls(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	for _, w := range []window{newTimeWindow(time.Minute, 6), newCountWindow(2)} {
+		w.record(now, false, true)
+		w.record(now, true, true)
+		if total, failures, slow := w.counts(now); total != 2 || failures != 1 || slow != 2 {
+			t.Error("incorrect counts", total, failures, slow)
+		}
+
+		w.record(now, false, false)
+		w.record(now.Add(time.Hour), false, false)
+		w.record(now.Add(time.Hour), false, false)
+		if total, failures, slow := w.counts(now.Add(time.Hour)); total != 2 || failures != 0 || slow != 0 {
+			t.Error("incorrect counts", total, failures, slow)
+		}
+	}
+}
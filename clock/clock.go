@@ -0,0 +1,61 @@
+// Package clock abstracts over the passage of time, so that code using the resiliency patterns
+// in this module can be tested quickly and deterministically.
+package clock
+
+import "time"
+
+// Clock is the interface implemented by anything that can tell the time and schedule work in the
+// future. Every pattern in this module accepts a Clock via its WithClock method.
+type Clock interface {
+	// Now returns the current time, like time.Now.
+	Now() time.Time
+	// Sleep pauses the current goroutine for at least the given duration, like time.Sleep.
+	Sleep(d time.Duration)
+	// NewTimer creates a new Timer which sends the current time on its channel after at
+	// least the given duration, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc waits for the given duration to elapse and then calls f, like time.AfterFunc.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the interface implemented by the timers returned from a Clock. It mirrors time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the timer fires. It is nil for
+	// timers created by AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, like time.Timer.Stop.
+	Stop() bool
+	// Reset changes the timer to expire after the given duration, like time.Timer.Reset.
+	Reset(d time.Duration) bool
+}
+
+// New returns a Clock backed by the standard time package.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
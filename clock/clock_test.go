@@ -0,0 +1,138 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRealClock(t *testing.T) {
+	c := New()
+
+	start := c.Now()
+	c.Sleep(1 * time.Millisecond)
+	if c.Now().Sub(start) < 1*time.Millisecond {
+		t.Error("slept too little")
+	}
+
+	timer := c.NewTimer(1 * time.Millisecond)
+	<-timer.C()
+	if timer.Stop() {
+		t.Error("stopped a fired timer")
+	}
+
+	fired := make(chan struct{})
+	timer = c.AfterFunc(1*time.Millisecond, func() { close(fired) })
+	<-fired
+	if timer.C() != nil {
+		t.Error("AfterFunc timer has a channel")
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewFake(start)
+
+	if !c.Now().Equal(start) {
+		t.Error("incorrect time")
+	}
+	c.Advance(time.Minute)
+	if !c.Now().Equal(start.Add(time.Minute)) {
+		t.Error("incorrect time")
+	}
+}
+
+func TestFakeTimers(t *testing.T) {
+	c := NewFake(time.Unix(1000, 0))
+
+	var order []int
+	t1 := c.NewTimer(2 * time.Second)
+	c.AfterFunc(3*time.Second, func() { order = append(order, 3) })
+	c.AfterFunc(1*time.Second, func() { order = append(order, 1) })
+	stopped := c.AfterFunc(1*time.Second, func() { t.Error("stopped timer fired") })
+	if !stopped.Stop() {
+		t.Error("failed to stop timer")
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if len(order) != 1 || order[0] != 1 {
+		t.Error("incorrect firing", order)
+	}
+	select {
+	case <-t1.C():
+		t.Error("timer fired early")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case when := <-t1.C():
+		if !when.Equal(time.Unix(1002, 0)) {
+			t.Error("incorrect firing time", when)
+		}
+	default:
+		t.Error("timer did not fire")
+	}
+
+	// a reset timer fires again
+	if t1.Reset(time.Second) {
+		t.Error("reset reported an active timer")
+	}
+	c.Advance(time.Second)
+	if len(order) != 2 || order[1] != 3 {
+		t.Error("incorrect firing", order)
+	}
+	select {
+	case <-t1.C():
+	default:
+		t.Error("timer did not fire")
+	}
+
+	// expired channel timers fire immediately, expired functions on the next advance
+	select {
+	case <-c.NewTimer(0).C():
+	default:
+		t.Error("timer did not fire")
+	}
+	ran := false
+	c.AfterFunc(0, func() { ran = true })
+	if ran {
+		t.Error("function ran synchronously")
+	}
+	c.Advance(0)
+	if !ran {
+		t.Error("function did not run")
+	}
+
+	// negative durations are already expired, without moving the clock backwards
+	now := c.Now()
+	c.AfterFunc(-time.Hour, func() {
+		if !c.Now().Equal(now) {
+			t.Error("clock went backwards", c.Now())
+		}
+	})
+	c.Advance(0)
+	if !c.Now().Equal(now) {
+		t.Error("clock went backwards", c.Now())
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	c := NewFake(time.Unix(1000, 0))
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Second)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Error("woke early")
+	default:
+	}
+
+	c.Advance(1 * time.Millisecond)
+	<-done
+}
@@ -0,0 +1,163 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when it is explicitly advanced, for use in tests. Timers
+// fire (and functions passed to AfterFunc are run) synchronously from within Advance, in the order
+// of their deadlines; a function scheduled with a non-positive duration runs on the next call to
+// Advance, which may be Advance(0). It is safe to use a Fake concurrently from multiple goroutines.
+type Fake struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a Fake clock whose current time is "now".
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.lock)
+	return f
+}
+
+// Now implements the Clock interface.
+func (f *Fake) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.now
+}
+
+// Sleep implements the Clock interface. It blocks until another goroutine advances the clock
+// by at least the given duration.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.NewTimer(d).C()
+}
+
+// NewTimer implements the Clock interface.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{
+		clock: f,
+		c:     make(chan time.Time, 1),
+	}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc implements the Clock interface.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{
+		clock: f,
+		fn:    fn,
+	}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by the given duration, firing any timers which expire in the
+// meantime.
+func (f *Fake) Advance(d time.Duration) {
+	f.lock.Lock()
+	end := f.now.Add(d)
+
+	for len(f.timers) > 0 && !f.timers[0].when.After(end) {
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		f.now = t.when
+		f.lock.Unlock()
+
+		t.fire()
+
+		f.lock.Lock()
+	}
+
+	f.now = end
+	f.lock.Unlock()
+}
+
+// BlockUntil blocks until at least n timers (including those created by Sleep and AfterFunc) are
+// waiting to fire. It is useful for waiting until another goroutine has started sleeping before
+// advancing the clock.
+func (f *Fake) BlockUntil(n int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
+
+func (f *Fake) schedule(t *fakeTimer) {
+	f.timers = append(f.timers, t)
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].when.Before(f.timers[j].when)
+	})
+	f.cond.Broadcast()
+}
+
+func (f *Fake) unschedule(t *fakeTimer) bool {
+	for i := range f.timers {
+		if f.timers[i] == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *Fake
+	when  time.Time
+	c     chan time.Time
+	fn    func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	active := t.clock.unschedule(t)
+	if d < 0 {
+		// as with real timers, already expired; and the clock must never go backwards
+		d = 0
+	}
+	t.when = t.clock.now.Add(d)
+	if d > 0 || t.fn != nil {
+		// functions are never run synchronously, even if already expired, since
+		// the caller may be holding locks that they need; they run on the next
+		// call to Advance instead
+		t.clock.schedule(t)
+		t.clock.lock.Unlock()
+		return active
+	}
+	t.clock.lock.Unlock()
+
+	// already expired
+	t.fire()
+	return active
+}
+
+func (t *fakeTimer) fire() {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+
+	select {
+	case t.c <- t.when:
+	default:
+	}
+}
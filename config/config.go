@@ -0,0 +1,26 @@
+// Package config provides types shared by the configuration structs of the resiliency patterns,
+// which allow each pattern to be constructed from a configuration file (for example with
+// breaker.FromConfig) rather than in code.
+package config
+
+import "time"
+
+// Duration is a time.Duration which is encoded as text in the format accepted by time.ParseDuration,
+// such as "1.5s" or "300ms", so that it can be written naturally in JSON and other configuration
+// formats which use encoding.TextUnmarshaler.
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
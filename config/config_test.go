@@ -0,0 +1,29 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	var d struct {
+		Timeout Duration `json:"timeout"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeout": "1.5s"}`), &d); err != nil {
+		t.Error(err)
+	}
+	if time.Duration(d.Timeout) != 1500*time.Millisecond {
+		t.Error("incorrect duration", d.Timeout)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil || string(data) != `{"timeout":"1.5s"}` {
+		t.Error(string(data), err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeout": "soon"}`), &d); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,18 @@
+package deadline
+
+import (
+	"time"
+
+	"github.com/eapache/go-resiliency/config"
+)
+
+// Config describes a Deadline in a form which can be loaded from a configuration file, for use with
+// FromConfig.
+type Config struct {
+	Timeout config.Duration `json:"timeout"`
+}
+
+// FromConfig constructs a Deadline as described by the given configuration.
+func FromConfig(c Config) *Deadline {
+	return New(time.Duration(c.Timeout))
+}
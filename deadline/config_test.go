@@ -0,0 +1,18 @@
+package deadline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFromConfig(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"timeout": "250ms"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := FromConfig(c); d.timeout != 250*time.Millisecond {
+		t.Error("incorrect timeout", d.timeout)
+	}
+}
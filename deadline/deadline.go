@@ -4,6 +4,8 @@ package deadline
 import (
 	"errors"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 // ErrTimedOut is the error returned from Run when the deadline expires.
@@ -12,15 +14,24 @@ var ErrTimedOut = errors.New("timed out waiting for function to finish")
 // Deadline implements the deadline/timeout resiliency pattern.
 type Deadline struct {
 	timeout time.Duration
+	clock   clock.Clock
 }
 
 // New constructs a new Deadline with the given timeout.
 func New(timeout time.Duration) *Deadline {
 	return &Deadline{
 		timeout: timeout,
+		clock:   clock.New(),
 	}
 }
 
+// WithClock configures the deadline to use the given clock instead of the real one, for example
+// a clock.Fake in tests.
+func (d *Deadline) WithClock(c clock.Clock) *Deadline {
+	d.clock = c
+	return d
+}
+
 // Run runs the given function, passing it a stopper channel. If the deadline passes before
 // the function finishes executing, Run returns ErrTimeOut to the caller and closes the stopper
 // channel so that the work function can attempt to exit gracefully. It does not (and cannot)
@@ -28,20 +39,38 @@ func New(timeout time.Duration) *Deadline {
 // then it may keep running after the deadline passes. If the function finishes before the
 // deadline, then the return value of the function is returned from Run.
 func (d *Deadline) Run(work func(<-chan struct{}) error) error {
-	result := make(chan error, 1)
+	_, err := Execute(d, func(stopper <-chan struct{}) (struct{}, error) {
+		return struct{}{}, work(stopper)
+	})
+	return err
+}
+
+type result[T any] struct {
+	val T
+	err error
+}
+
+// Execute is like Deadline.Run, except that the work function also returns a value, which is
+// passed along to the caller. If the deadline passes before the function finishes executing,
+// the zero value of T is returned along with ErrTimedOut, and the value eventually returned by
+// the function is discarded.
+func Execute[T any](d *Deadline, work func(<-chan struct{}) (T, error)) (T, error) {
+	results := make(chan result[T], 1)
 	stopper := make(chan struct{})
 
 	go func() {
-		result <- work(stopper)
+		val, err := work(stopper)
+		results <- result[T]{val, err}
 	}()
 
-	timer := time.NewTimer(d.timeout)
+	timer := d.clock.NewTimer(d.timeout)
 	select {
-	case ret := <-result:
+	case ret := <-results:
 		timer.Stop()
-		return ret
-	case <-timer.C:
+		return ret.val, ret.err
+	case <-timer.C():
 		close(stopper)
-		return ErrTimedOut
+		var zero T
+		return zero, ErrTimedOut
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 func takesFiveMillis(stopper <-chan struct{}) error {
@@ -47,6 +49,53 @@ func TestDeadline(t *testing.T) {
 	<-done
 }
 
+func TestExecute(t *testing.T) {
+	dl := New(10 * time.Millisecond)
+
+	val, err := Execute(dl, func(stopper <-chan struct{}) (int, error) {
+		return 42, nil
+	})
+	if val != 42 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = Execute(dl, func(stopper <-chan struct{}) (int, error) {
+		return 7, errors.New("foo")
+	})
+	if val != 7 || err.Error() != "foo" {
+		t.Error(val, err)
+	}
+
+	val, err = Execute(dl, func(stopper <-chan struct{}) (int, error) {
+		<-stopper
+		return 42, nil
+	})
+	if val != 0 || err != ErrTimedOut {
+		t.Error(val, err)
+	}
+}
+
+func TestDeadlineClock(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	dl := New(1 * time.Hour).WithClock(c)
+
+	go func() {
+		c.BlockUntil(1)
+		c.Advance(1 * time.Hour)
+	}()
+
+	stopped := make(chan struct{})
+	err := dl.Run(func(stopper <-chan struct{}) error {
+		<-stopper
+		close(stopped)
+		return nil
+	})
+	if err != ErrTimedOut {
+		t.Error(err)
+	}
+	<-stopped
+}
+
 func ExampleDeadline() {
 	dl := New(1 * time.Second)
 
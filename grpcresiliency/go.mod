@@ -0,0 +1,21 @@
+module github.com/eapache/go-resiliency/grpcresiliency
+
+go 1.25.0
+
+require (
+	github.com/eapache/go-resiliency v1.8.0
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+// For local development only; Go ignores this when the module is used as a dependency. The version
+// required above is the core release which introduces the APIs this module uses, and must be tagged
+// before this module is.
+replace github.com/eapache/go-resiliency => ../
@@ -0,0 +1,194 @@
+// Package grpcresiliency adapts the circuit-breaker and retriable resiliency patterns to gRPC, by
+// providing client and server interceptors which run calls through a breaker.Breaker and/or a
+// retrier.Retrier. It lives in a separate module so that the core patterns remain free of
+// dependencies.
+package grpcresiliency
+
+import (
+	"context"
+
+	"github.com/eapache/go-resiliency/breaker"
+	"github.com/eapache/go-resiliency/retrier"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CodeClassifier classifies errors returned by gRPC calls based on their status code. If the error
+// is nil, it returns Succeed; if the error's status code is in the list, it returns Retry; otherwise
+// (including for errors which are not gRPC statuses, such as breaker.ErrBreakerOpen) it returns Fail.
+type CodeClassifier []codes.Code
+
+// Classify implements the retrier.Classifier interface.
+func (list CodeClassifier) Classify(err error) retrier.Action {
+	if err == nil {
+		return retrier.Succeed
+	}
+
+	code := status.Code(err)
+	for _, pass := range list {
+		if code == pass {
+			return retrier.Retry
+		}
+	}
+
+	return retrier.Fail
+}
+
+// DefaultClassifier is the classifier used by the interceptors unless configured otherwise. It treats
+// the status codes which indicate a struggling or unreachable server as failures to be retried.
+var DefaultClassifier = CodeClassifier{codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded}
+
+// ClientInterceptor provides gRPC client interceptors which run each call through a circuit-breaker
+// and/or a retrier. Each attempt made by the retrier is run through the breaker separately; when the
+// breaker is open, attempts fail immediately with breaker.ErrBreakerOpen without being sent, which
+// the retrier should classify as Fail (as DefaultClassifier does) so that it gives up straight away.
+type ClientInterceptor struct {
+	breaker *breaker.Breaker
+	retrier *retrier.Retrier
+	class   retrier.Classifier
+}
+
+// NewClient constructs a ClientInterceptor using the given breaker and retrier, either of which may
+// be nil to skip that pattern. The retrier decides which errors to retry using its own classifier, so
+// it should normally be constructed with DefaultClassifier or another CodeClassifier.
+func NewClient(b *breaker.Breaker, r *retrier.Retrier) *ClientInterceptor {
+	return &ClientInterceptor{
+		breaker: b,
+		retrier: r,
+		class:   DefaultClassifier,
+	}
+}
+
+// WithClassifier configures the classifier used to decide whether the outcome of a call counts as a
+// failure for the breaker. Errors classified as Retry are failures; everything else counts as a
+// success, since the server responded. The error is always passed along to the caller unchanged.
+func (c *ClientInterceptor) WithClassifier(class retrier.Classifier) *ClientInterceptor {
+	c.class = class
+	return c
+}
+
+// Unary returns a grpc.UnaryClientInterceptor, for use with grpc.WithUnaryInterceptor.
+func (c *ClientInterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return c.run(ctx, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// Stream returns a grpc.StreamClientInterceptor, for use with grpc.WithStreamInterceptor. Only the
+// creation of the stream is run through the breaker and retrier; errors from sending or receiving
+// messages on an established stream are returned to the caller without being counted or retried.
+func (c *ClientInterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var stream grpc.ClientStream
+		err := c.run(ctx, func(ctx context.Context) (err error) {
+			stream, err = streamer(ctx, desc, cc, method, opts...)
+			return err
+		})
+		return stream, err
+	}
+}
+
+func (c *ClientInterceptor) run(ctx context.Context, call func(ctx context.Context) error) error {
+	if c.retrier == nil {
+		return c.attempt(ctx, call)
+	}
+	return c.retrier.RunCtx(ctx, func(ctx context.Context) error {
+		return c.attempt(ctx, call)
+	})
+}
+
+func (c *ClientInterceptor) attempt(ctx context.Context, call func(ctx context.Context) error) error {
+	if c.breaker == nil {
+		return call(ctx)
+	}
+
+	var callErr error
+	ran := false
+
+	err := c.breaker.RunCtx(ctx, func(ctx context.Context) error {
+		ran = true
+		callErr = call(ctx)
+		if c.class.Classify(callErr) != retrier.Retry {
+			return nil
+		}
+		return callErr
+	})
+
+	if !ran {
+		return err
+	}
+	return callErr
+}
+
+// ServerInterceptor provides gRPC server interceptors which run each call through a circuit-breaker,
+// so that a server whose handlers are failing (for example because a database is down) sheds load
+// rather than continuing to accept calls. When the breaker is open, calls are rejected with status
+// code Unavailable without reaching the handler; calls whose context is already done are rejected
+// with Canceled or DeadlineExceeded as appropriate.
+type ServerInterceptor struct {
+	breaker *breaker.Breaker
+	class   retrier.Classifier
+}
+
+// NewServer constructs a ServerInterceptor using the given breaker.
+func NewServer(b *breaker.Breaker) *ServerInterceptor {
+	return &ServerInterceptor{
+		breaker: b,
+		class:   DefaultClassifier,
+	}
+}
+
+// WithClassifier configures the classifier used to decide whether the error returned by a handler
+// counts as a failure for the breaker, as in ClientInterceptor.WithClassifier.
+func (s *ServerInterceptor) WithClassifier(class retrier.Classifier) *ServerInterceptor {
+	s.class = class
+	return s
+}
+
+// Unary returns a grpc.UnaryServerInterceptor, for use with grpc.UnaryInterceptor.
+func (s *ServerInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var resp interface{}
+		err := s.run(ctx, func(ctx context.Context) (err error) {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// Stream returns a grpc.StreamServerInterceptor, for use with grpc.StreamInterceptor. The whole
+// stream counts as a single call.
+func (s *ServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return s.run(ss.Context(), func(ctx context.Context) error {
+			return handler(srv, ss)
+		})
+	}
+}
+
+func (s *ServerInterceptor) run(ctx context.Context, handle func(ctx context.Context) error) error {
+	var handleErr error
+	ran := false
+
+	err := s.breaker.RunCtx(ctx, func(ctx context.Context) error {
+		ran = true
+		handleErr = handle(ctx)
+		if s.class.Classify(handleErr) != retrier.Retry {
+			return nil
+		}
+		return handleErr
+	})
+
+	if !ran {
+		if err == breaker.ErrBreakerOpen {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		// the call was cancelled or timed out before it could be run
+		return status.FromContextError(err).Err()
+	}
+	return handleErr
+}
@@ -0,0 +1,223 @@
+package grpcresiliency
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+	"github.com/eapache/go-resiliency/retrier"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer fails every call with the configured status code (or succeeds if it is OK).
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	code  int32
+	calls int32
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	atomic.AddInt32(&h.calls, 1)
+	if code := codes.Code(atomic.LoadInt32(&h.code)); code != codes.OK {
+		return nil, status.Error(code, "failed")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	atomic.AddInt32(&h.calls, 1)
+	if code := codes.Code(atomic.LoadInt32(&h.code)); code != codes.OK {
+		return status.Error(code, "failed")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func (h *healthServer) set(code codes.Code) {
+	atomic.StoreInt32(&h.code, int32(code))
+}
+
+func (h *healthServer) count() int32 {
+	return atomic.SwapInt32(&h.calls, 0)
+}
+
+func newConn(t *testing.T, h *healthServer, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) healthpb.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(server, h)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func check(client healthpb.HealthClient) error {
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func watch(client healthpb.HealthClient) error {
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func TestCodeClassifier(t *testing.T) {
+	class := CodeClassifier{codes.Unavailable}
+
+	if class.Classify(nil) != retrier.Succeed {
+		t.Error("nil did not succeed")
+	}
+	if class.Classify(status.Error(codes.Unavailable, "")) != retrier.Retry {
+		t.Error("unavailable did not retry")
+	}
+	if class.Classify(status.Error(codes.NotFound, "")) != retrier.Fail {
+		t.Error("not found did not fail")
+	}
+	if class.Classify(breaker.ErrBreakerOpen) != retrier.Fail {
+		t.Error("breaker open did not fail")
+	}
+}
+
+func TestClientUnary(t *testing.T) {
+	h := &healthServer{}
+	b := breaker.New(3, 1, time.Hour)
+	r := retrier.New(retrier.ConstantBackoff(1, time.Millisecond), DefaultClassifier)
+	client := newConn(t, h, nil, grpc.WithUnaryInterceptor(NewClient(b, r).Unary()))
+
+	if err := check(client); err != nil {
+		t.Error(err)
+	}
+
+	// other codes are neither retried nor count as failures
+	h.set(codes.NotFound)
+	for i := 0; i < 3; i++ {
+		if status.Code(check(client)) != codes.NotFound {
+			t.Error("incorrect code")
+		}
+	}
+	if h.count() != 4 || b.GetState() != breaker.Closed {
+		t.Error("incorrect calls or state")
+	}
+
+	// each attempt counts against the breaker
+	h.set(codes.Unavailable)
+	if status.Code(check(client)) != codes.Unavailable {
+		t.Error("incorrect code")
+	}
+	if h.count() != 2 || b.GetState() != breaker.Closed {
+		t.Error("incorrect calls or state")
+	}
+
+	// once open, calls fail fast and are not retried
+	if err := check(client); err != breaker.ErrBreakerOpen {
+		t.Error(err)
+	}
+	if h.count() != 1 || b.GetState() != breaker.Open {
+		t.Error("incorrect calls or state")
+	}
+	if err := check(client); err != breaker.ErrBreakerOpen {
+		t.Error(err)
+	}
+	if h.count() != 0 {
+		t.Error("call reached server")
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	h := &healthServer{}
+	b := breaker.New(1, 1, time.Hour)
+	client := newConn(t, h, nil, grpc.WithStreamInterceptor(NewClient(b, nil).Stream()))
+
+	if err := watch(client); err != nil {
+		t.Error(err)
+	}
+
+	b.ForceOpen()
+	if err := watch(client); err != breaker.ErrBreakerOpen {
+		t.Error(err)
+	}
+	if h.count() != 1 {
+		t.Error("incorrect calls")
+	}
+}
+
+func TestServer(t *testing.T) {
+	h := &healthServer{}
+	b := breaker.New(1, 1, time.Hour)
+	interceptor := NewServer(b)
+	client := newConn(t, h, []grpc.ServerOption{
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	})
+
+	h.set(codes.NotFound)
+	if status.Code(check(client)) != codes.NotFound || b.GetState() != breaker.Closed {
+		t.Error("incorrect code or state")
+	}
+
+	h.set(codes.ResourceExhausted)
+	if status.Code(watch(client)) != codes.ResourceExhausted || b.GetState() != breaker.Open {
+		t.Error("incorrect code or state")
+	}
+
+	// the open breaker sheds calls before they reach the handler
+	h.set(codes.OK)
+	h.count()
+	if status.Code(check(client)) != codes.Unavailable {
+		t.Error("incorrect code")
+	}
+	if status.Code(watch(client)) != codes.Unavailable {
+		t.Error("incorrect code")
+	}
+	if h.count() != 0 {
+		t.Error("call reached handler")
+	}
+}
+
+func TestServerContextDone(t *testing.T) {
+	b := breaker.New(1, 1, time.Hour)
+	unary := NewServer(b).Unary()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("call reached handler")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, handler); status.Code(err) != codes.Canceled {
+		t.Error("incorrect code", err)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, handler); status.Code(err) != codes.DeadlineExceeded {
+		t.Error("incorrect code", err)
+	}
+
+	if b.GetState() != breaker.Closed {
+		t.Error("incorrect state")
+	}
+}
@@ -0,0 +1,77 @@
+package retrier
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eapache/go-resiliency/config"
+)
+
+// BackoffConfig describes a back-off strategy in a form which can be loaded from a configuration
+// file. The strategy names each of the back-off generators in this package: "constant"
+// (ConstantBackoff), "exponential" (ExponentialBackoff) and "limited-exponential"
+// (LimitedExponentialBackoff).
+type BackoffConfig struct {
+	// Strategy is the name of the back-off generator.
+	Strategy string `json:"strategy"`
+	// Retries is the number of retries, i.e. the length of the back-off.
+	Retries int `json:"retries"`
+	// Initial is the amount of time waited before the first retry (or every retry, for "constant").
+	Initial config.Duration `json:"initial"`
+	// Limit caps the amount of time waited before each retry, for "limited-exponential".
+	Limit config.Duration `json:"limit,omitempty"`
+}
+
+// Backoff generates the back-off described by the configuration. It returns an error if the strategy
+// is unknown or if the number of retries or either duration is negative.
+func (c BackoffConfig) Backoff() ([]time.Duration, error) {
+	if c.Retries < 0 {
+		return nil, fmt.Errorf("retrier: invalid number of retries %d", c.Retries)
+	}
+	if c.Initial < 0 || c.Limit < 0 {
+		return nil, errors.New("retrier: back-off durations must not be negative")
+	}
+
+	switch c.Strategy {
+	case "constant":
+		return ConstantBackoff(c.Retries, time.Duration(c.Initial)), nil
+	case "exponential":
+		return ExponentialBackoff(c.Retries, time.Duration(c.Initial)), nil
+	case "limited-exponential":
+		return LimitedExponentialBackoff(c.Retries, time.Duration(c.Initial), time.Duration(c.Limit)), nil
+	default:
+		return nil, fmt.Errorf("retrier: unknown back-off strategy %q", c.Strategy)
+	}
+}
+
+// Config describes a Retrier in a form which can be loaded from a configuration file, for use with
+// FromConfig.
+type Config struct {
+	Backoff           BackoffConfig `json:"backoff"`
+	Jitter            float64       `json:"jitter,omitempty"`
+	InfiniteRetry     bool          `json:"infiniteRetry,omitempty"`
+	SurfaceWorkErrors bool          `json:"surfaceWorkErrors,omitempty"`
+}
+
+// FromConfig constructs a Retrier as described by the given configuration, using the given
+// classifier as in New. It returns an error if the configuration is invalid.
+func FromConfig(c Config, class Classifier) (*Retrier, error) {
+	backoff, err := c.Backoff.Backoff()
+	if err != nil {
+		return nil, err
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return nil, fmt.Errorf("retrier: jitter %v is outside the range 0.0 to 1.0", c.Jitter)
+	}
+
+	r := New(backoff, class)
+	r.SetJitter(c.Jitter)
+	if c.InfiniteRetry {
+		r.WithInfiniteRetry()
+	}
+	if c.SurfaceWorkErrors {
+		r.WithSurfaceWorkErrors()
+	}
+	return r, nil
+}
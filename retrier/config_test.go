@@ -0,0 +1,60 @@
+package retrier
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFromConfig(t *testing.T) {
+	var c Config
+	err := json.Unmarshal([]byte(`{
+		"backoff": {"strategy": "limited-exponential", "retries": 4, "initial": "10ms", "limit": "30ms"},
+		"jitter": 0.25,
+		"surfaceWorkErrors": true
+	}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := FromConfig(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}
+	for i := range expected {
+		if r.backoff[i] != expected[i] {
+			t.Error("incorrect value at", i)
+		}
+	}
+	if r.jitter != 0.25 || !r.surfaceWorkErrors || r.infiniteRetry {
+		t.Error("incorrect options")
+	}
+	if _, ok := r.class.(DefaultClassifier); !ok {
+		t.Error("incorrect classifier")
+	}
+
+	for _, strategy := range []string{"constant", "exponential"} {
+		if _, err := FromConfig(Config{Backoff: BackoffConfig{Strategy: strategy, Retries: 1}}, nil); err != nil {
+			t.Error(strategy, err)
+		}
+	}
+
+	if _, err := FromConfig(Config{Backoff: BackoffConfig{Strategy: "fibonacci"}}, nil); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := FromConfig(Config{Backoff: BackoffConfig{Strategy: "constant"}, Jitter: 2}, nil); err == nil {
+		t.Error("expected error")
+	}
+
+	invalid := []BackoffConfig{
+		{Strategy: "constant", Retries: -1},
+		{Strategy: "exponential", Retries: 1, Initial: -1},
+		{Strategy: "limited-exponential", Retries: 1, Initial: 1, Limit: -1},
+	}
+	for i, c := range invalid {
+		if _, err := c.Backoff(); err == nil {
+			t.Error("expected error for", i)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 // Retrier implements the "retriable" resiliency pattern, abstracting out the process of retrying a failed action
@@ -18,6 +20,7 @@ type Retrier struct {
 	jitter            float64
 	rand              *rand.Rand
 	randMu            sync.Mutex
+	clock             clock.Clock
 }
 
 // New constructs a Retrier with the given backoff pattern and classifier. The length of the backoff pattern
@@ -33,6 +36,7 @@ func New(backoff []time.Duration, class Classifier) *Retrier {
 		backoff: backoff,
 		class:   class,
 		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		clock:   clock.New(),
 	}
 }
 
@@ -51,6 +55,13 @@ func (r *Retrier) WithSurfaceWorkErrors() *Retrier {
 	return r
 }
 
+// WithClock configures the retrier to use the given clock instead of the real one when waiting
+// between retries, for example a clock.Fake in tests.
+func (r *Retrier) WithClock(c clock.Clock) *Retrier {
+	r.clock = c
+	return r
+}
+
 // Run executes the given work function by executing RunCtx without context.Context.
 func (r *Retrier) Run(work func() error) error {
 	return r.RunFn(context.Background(), func(c context.Context, r int) error {
@@ -89,7 +100,7 @@ func (r *Retrier) RunFn(ctx context.Context, work func(ctx context.Context, retr
 				return ret
 			}
 
-			timer := time.NewTimer(r.calcSleep(retries))
+			timer := r.clock.NewTimer(r.calcSleep(retries))
 			if err := r.sleep(ctx, timer); err != nil {
 				if r.surfaceWorkErrors {
 					return ret
@@ -102,9 +113,22 @@ func (r *Retrier) RunFn(ctx context.Context, work func(ctx context.Context, retr
 	}
 }
 
-func (r *Retrier) sleep(ctx context.Context, timer *time.Timer) error {
+// Do is like Retrier.RunFn, except that the work function also returns a value. The value returned
+// from the final attempt is passed along to the caller, alongside the error that RunFn would have
+// returned.
+func Do[T any](ctx context.Context, r *Retrier, work func(ctx context.Context, retries int) (T, error)) (T, error) {
+	var ret T
+	err := r.RunFn(ctx, func(ctx context.Context, retries int) error {
+		var err error
+		ret, err = work(ctx, retries)
+		return err
+	})
+	return ret, err
+}
+
+func (r *Retrier) sleep(ctx context.Context, timer clock.Timer) error {
 	select {
-	case <-timer.C:
+	case <-timer.C():
 		return nil
 	case <-ctx.Done():
 		timer.Stop()
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 var i int
@@ -199,6 +201,55 @@ func TestRetrierRunFnWithoutSurfaceWorkErrors(t *testing.T) {
 	}
 }
 
+func TestDo(t *testing.T) {
+	r := New([]time.Duration{0, 10 * time.Millisecond}, WhitelistClassifier{errFoo})
+
+	val, err := Do(context.Background(), r, func(ctx context.Context, retries int) (int, error) {
+		if retries < 2 {
+			return retries, errFoo
+		}
+		return retries, nil
+	})
+	if val != 2 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = Do(context.Background(), r, func(ctx context.Context, retries int) (int, error) {
+		return retries, errBar
+	})
+	if val != 0 || err != errBar {
+		t.Error(val, err)
+	}
+
+	val, err = Do(context.Background(), r, func(ctx context.Context, retries int) (int, error) {
+		return retries, errFoo
+	})
+	if val != 2 || err != errFoo {
+		t.Error(val, err)
+	}
+}
+
+func TestRetrierClock(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	r := New(ConstantBackoff(2, 1*time.Hour), nil).WithClock(c)
+
+	result := make(chan error)
+	go func() {
+		result <- r.Run(genWork([]error{errFoo, errFoo}))
+	}()
+
+	for i := 0; i < 2; i++ {
+		c.BlockUntil(1)
+		c.Advance(1 * time.Hour)
+	}
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	if i != 3 {
+		t.Error("run wrong number of times")
+	}
+}
+
 func TestRetrierNone(t *testing.T) {
 	r := New(nil, nil)
 
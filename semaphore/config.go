@@ -0,0 +1,24 @@
+package semaphore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eapache/go-resiliency/config"
+)
+
+// Config describes a Semaphore in a form which can be loaded from a configuration file, for use
+// with FromConfig.
+type Config struct {
+	Tickets int             `json:"tickets"`
+	Timeout config.Duration `json:"timeout"`
+}
+
+// FromConfig constructs a Semaphore as described by the given configuration. It returns an error if
+// the configuration has no tickets, since such a semaphore could never be acquired.
+func FromConfig(c Config) (*Semaphore, error) {
+	if c.Tickets < 1 {
+		return nil, fmt.Errorf("semaphore: invalid ticket count %d", c.Tickets)
+	}
+	return New(c.Tickets, time.Duration(c.Timeout)), nil
+}
@@ -0,0 +1,26 @@
+package semaphore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFromConfig(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"tickets": 3, "timeout": "1s"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := FromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cap(s.sem) != 3 || s.timeout != time.Second {
+		t.Error("incorrect semaphore")
+	}
+
+	if _, err := FromConfig(Config{}); err == nil {
+		t.Error("expected error")
+	}
+}
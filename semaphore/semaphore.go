@@ -4,6 +4,8 @@ package semaphore
 import (
 	"errors"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 // ErrNoTickets is the error returned by Acquire when it could not acquire
@@ -14,6 +16,7 @@ var ErrNoTickets = errors.New("could not acquire semaphore ticket")
 type Semaphore struct {
 	sem     chan struct{}
 	timeout time.Duration
+	clock   clock.Clock
 }
 
 // New constructs a new Semaphore with the given ticket-count
@@ -22,19 +25,27 @@ func New(tickets int, timeout time.Duration) *Semaphore {
 	return &Semaphore{
 		sem:     make(chan struct{}, tickets),
 		timeout: timeout,
+		clock:   clock.New(),
 	}
 }
 
+// WithClock configures the semaphore to use the given clock instead of the real one, for example
+// a clock.Fake in tests.
+func (s *Semaphore) WithClock(c clock.Clock) *Semaphore {
+	s.clock = c
+	return s
+}
+
 // Acquire tries to acquire a ticket from the semaphore. If it can, it returns nil.
 // If it cannot after "timeout" amount of time, it returns ErrNoTickets. It is
 // safe to call Acquire concurrently on a single Semaphore.
 func (s *Semaphore) Acquire() error {
-	timer := time.NewTimer(s.timeout)
+	timer := s.clock.NewTimer(s.timeout)
 	select {
 	case s.sem <- struct{}{}:
 		timer.Stop()
 		return nil
-	case <-timer.C:
+	case <-timer.C():
 		return ErrNoTickets
 	}
 }
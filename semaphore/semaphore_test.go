@@ -3,6 +3,8 @@ package semaphore
 import (
 	"testing"
 	"time"
+
+	"github.com/eapache/go-resiliency/clock"
 )
 
 func TestSemaphoreAcquireRelease(t *testing.T) {
@@ -45,6 +47,26 @@ func TestSemaphoreBlockTimeout(t *testing.T) {
 	}
 }
 
+func TestSemaphoreClock(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	sem := New(1, 1*time.Hour).WithClock(c)
+
+	if err := sem.Acquire(); err != nil {
+		t.Error(err)
+	}
+
+	result := make(chan error)
+	go func() {
+		result <- sem.Acquire()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(1 * time.Hour)
+	if err := <-result; err != ErrNoTickets {
+		t.Error(err)
+	}
+}
+
 func TestSemaphoreEmpty(t *testing.T) {
 	sem := New(2, 200*time.Millisecond)
 